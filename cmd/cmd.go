@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
 	"github.com/fioncat/kubewrap/pkg/kubectl"
 	"github.com/fioncat/kubewrap/pkg/term"
 	"github.com/spf13/cobra"
@@ -46,7 +47,7 @@ func Build(c *cobra.Command, opts Options) *cobra.Command {
 			return term.PrintJson(cfg)
 		}
 
-		kubectl := kubectl.NewCommand(cfg.Kubectl.Name, cfg.Kubectl.Args)
+		kubectl := kubectl.NewCommand(cfg.Kubectl.Name, KubectlArgs(cfg))
 		cmdctx := &Context{
 			Command: cmd,
 			Config:  cfg,
@@ -62,3 +63,15 @@ func Build(c *cobra.Command, opts Options) *cobra.Command {
 
 	return c
 }
+
+// KubectlArgs returns the kubectl args in config, with the context selected by
+// session.
+func KubectlArgs(cfg *config.Config) []string {
+	context := kubeconfig.GetCurrentContext()
+	if context == "" {
+		return cfg.Kubectl.Args
+	}
+	args := make([]string, 0, len(cfg.Kubectl.Args)+2)
+	args = append(args, cfg.Kubectl.Args...)
+	return append(args, "--context", context)
+}
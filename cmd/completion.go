@@ -29,7 +29,7 @@ func GetCompleteKubeconfigManager(c *cobra.Command) kubeconfig.Manager {
 		return nil
 	}
 
-	mgr, err := kubeconfig.NewManager(cfg.KubeConfig.Root, cfg.KubeConfig.Alias)
+	mgr, err := kubeconfig.NewManager(cfg)
 	if err != nil {
 		WriteCompleteLogs("Create kubeconfig manager failed: %v", err)
 		return nil
@@ -50,7 +50,7 @@ func getCompleteKubectl(c *cobra.Command) kubectl.Kubectl {
 		return nil
 	}
 
-	return kubectl.NewCommand(cfg.Kubectl.Name, cfg.Kubectl.Args)
+	return kubectl.NewCommand(cfg.Kubectl.Name, KubectlArgs(cfg))
 }
 
 func CompleteNodeItems(c *cobra.Command) ([]string, bool) {
@@ -95,7 +95,7 @@ func completeResource(c *cobra.Command, toComplete string, fromContainer bool) (
 
 	case 2:
 		resourceType := fields[0]
-		namespace := getCurrentNamespace()
+		namespace := GetCurrentNamespace()
 		k := getCompleteKubectl(c)
 		if k == nil {
 			return nil, cobra.ShellCompDirectiveError
@@ -136,7 +136,7 @@ func CompleteContainer(c *cobra.Command, toComplete string) ([]string, cobra.She
 	case 3:
 		r := &kubectl.Resource{
 			Type:      fields[0],
-			Namespace: getCurrentNamespace(),
+			Namespace: GetCurrentNamespace(),
 			Name:      fields[1],
 		}
 		k := getCompleteKubectl(c)
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/secret"
+	"github.com/fioncat/kubewrap/pkg/term"
+)
+
+type checkResult struct {
+	Name string `json:"name"`
+
+	Items []*kubeconfig.CheckItem `json:"items"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func (o *Options) handleCheck() error {
+	var kcs []*kubeconfig.KubeConfig
+	if o.name != "" {
+		kc, ok := o.configMgr.Get(o.name)
+		if !ok {
+			return fmt.Errorf("cannot find kubeconfig %q to check", o.name)
+		}
+		kcs = []*kubeconfig.KubeConfig{kc}
+	} else {
+		kcs = o.listConfigs()
+	}
+
+	results := make([]*checkResult, 0, len(kcs))
+	for _, kc := range kcs {
+		if kc.Alias != "" && o.name == "" {
+			// The target of alias is already checked
+			continue
+		}
+		result := &checkResult{Name: kc.Name}
+		items, err := o.checkOne(kc)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Items = items
+		results = append(results, result)
+	}
+
+	if o.json {
+		return term.PrintJson(results)
+	}
+
+	rows := [][]string{{"NAME", "KIND", "SUBJECT", "EXPIRES"}}
+	colors := []*color.Color{color.New(color.Bold)}
+	for _, result := range results {
+		if result.Error != "" {
+			rows = append(rows, []string{result.Name, "-", "-", result.Error})
+			colors = append(colors, color.New(color.FgRed))
+			continue
+		}
+		if len(result.Items) == 0 {
+			rows = append(rows, []string{result.Name, "-", "-", "no credential found"})
+			colors = append(colors, color.New(color.Faint))
+			continue
+		}
+		for _, item := range result.Items {
+			expires, c := o.formatExpiry(item.ExpireAt)
+			switch {
+			case item.Error != "":
+				expires, c = item.Error, color.New(color.FgRed)
+			case item.Kind == kubeconfig.CheckKindExec:
+				expires, c = fmt.Sprintf("exec: %s", item.Detail), color.New(color.FgCyan)
+			}
+			rows = append(rows, []string{result.Name, item.Kind, item.Subject, expires})
+			colors = append(colors, c)
+		}
+	}
+
+	for i, line := range term.FormatTable(rows) {
+		fmt.Println(colors[i].Sprint(line))
+	}
+	return nil
+}
+
+func (o *Options) checkOne(kc *kubeconfig.KubeConfig) ([]*kubeconfig.CheckItem, error) {
+	data, err := o.configMgr.Read(kc.Name)
+	if err != nil {
+		return nil, err
+	}
+	return kubeconfig.Check(data, filepath.Dir(kc.Path()))
+}
+
+// expiryMark returns a mark for kubeconfig that expires within the expire
+// days, empty string if not. The encrypted files are skipped, listing should
+// not ask for the passphrase, use `--check` for them.
+func (o *Options) expiryMark(kc *kubeconfig.KubeConfig) string {
+	data, err := os.ReadFile(kc.Path())
+	if err != nil || secret.IsEncrypted(data) {
+		return ""
+	}
+	items, err := kubeconfig.Check(data, filepath.Dir(kc.Path()))
+	if err != nil {
+		return ""
+	}
+	expireAt := kubeconfig.EarliestExpiry(items)
+	if expireAt == nil {
+		return ""
+	}
+	if time.Until(*expireAt) > o.expireDuration() {
+		return ""
+	}
+
+	text, c := o.formatExpiry(expireAt)
+	return c.Sprintf("[%s]", text)
+}
+
+func (o *Options) formatExpiry(expireAt *time.Time) (string, *color.Color) {
+	if expireAt == nil {
+		return "never", color.New(color.FgGreen)
+	}
+
+	date := expireAt.Local().Format("2006-01-02")
+	left := time.Until(*expireAt)
+	switch {
+	case left <= 0:
+		return fmt.Sprintf("expired %s ago (%s)", formatDays(-left), date), color.New(color.FgRed)
+	case left <= o.expireDuration():
+		return fmt.Sprintf("expires in %s (%s)", formatDays(left), date), color.New(color.FgYellow)
+	default:
+		return fmt.Sprintf("expires in %s (%s)", formatDays(left), date), color.New(color.FgGreen)
+	}
+}
+
+func (o *Options) expireDuration() time.Duration {
+	return time.Duration(o.expireDays) * time.Hour * 24
+}
+
+func formatDays(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", days)
+}
@@ -29,8 +29,12 @@ func CompletionFunc(c *cobra.Command, args []string, toComplete string) ([]strin
 			continue
 		}
 		item := kc.Name
-		if kc.Alias != "" {
+		desc := mgr.GetMetadata(kc.Name).Description
+		switch {
+		case kc.Alias != "":
 			item = fmt.Sprintf("%s\talias to %s", kc.Name, kc.Alias)
+		case desc != "":
+			item = fmt.Sprintf("%s\t%s", kc.Name, desc)
 		}
 		items = append(items, item)
 	}
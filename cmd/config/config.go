@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/fioncat/kubewrap/cmd"
@@ -19,9 +20,18 @@ import (
 func New() *cobra.Command {
 	var opts Options
 	c := &cobra.Command{
-		Use:   "config [NAME]",
+		Use:   "config [NAME] [TARGET]",
 		Short: "Manage kube config files",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Manage kube config files.
+
+Use '@QUERY' as NAME to jump to the most frequently and recently used
+kubeconfig that fuzzy matches QUERY, without fzf.
+
+The TARGET is only used by some modes:
+  --rename OLD NEW       rename kubeconfig OLD to NEW
+  --copy SRC DEST        copy kubeconfig SRC to DEST
+  --alias add ALIAS NAME add an alias to kubeconfig NAME`,
+		Args: cobra.MaximumNArgs(2),
 
 		ValidArgsFunction: CompletionFunc,
 	}
@@ -32,14 +42,30 @@ func New() *cobra.Command {
 	c.Flags().BoolVarP(&opts.list, "list", "l", false, "list kubeconfig files")
 	c.Flags().BoolVarP(&opts.listHistory, "list-history", "H", false, "show kubeconfig history")
 	c.Flags().BoolVarP(&opts.unuse, "unuse", "u", false, "unuse current kubeconfig")
+	c.Flags().BoolVarP(&opts.rename, "rename", "", false, "rename kubeconfig, and its history records")
+	c.Flags().BoolVarP(&opts.copy, "copy", "", false, "copy kubeconfig to a new name")
+	c.Flags().StringVarP(&opts.alias, "alias", "", "", "manage aliases without editing config file, one of: add|remove|list")
+	c.Flags().StringSliceVarP(&opts.tags, "tag", "t", nil, "filter kubeconfig files by tags, in 'key=value' or 'key' format")
+	c.Flags().StringSliceVarP(&opts.tagAdd, "tag-add", "", nil, "add tags to kubeconfig, in 'key=value' format")
+	c.Flags().StringSliceVarP(&opts.tagRemove, "tag-remove", "", nil, "remove tags from kubeconfig by keys")
+	c.Flags().StringVarP(&opts.desc, "desc", "", "", "set description of kubeconfig, empty to clear")
+	c.Flags().BoolVarP(&opts.check, "check", "", false, "check the expiry of credentials in kubeconfig files")
+
+	c.Flags().BoolVarP(&opts.health, "health", "", false, "probe clusters of kubeconfig files, check reachability and auth")
+
+	c.Flags().BoolVarP(&opts.json, "json", "", false, "print check or health report in json format")
+	c.Flags().IntVarP(&opts.expireDays, "expire-days", "", 30, "mark kubeconfig files that expire within these days")
 
 	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
 
+	c.AddCommand(newDoctor(), newSync(), newExport())
+
 	return cmd.Build(c, &opts)
 }
 
 type Options struct {
-	name string
+	name   string
+	target string
 
 	edit      bool
 	delete    bool
@@ -50,33 +76,59 @@ type Options struct {
 
 	unuse bool
 
+	rename bool
+	copy   bool
+	alias  string
+
+	tags      []string
+	tagAdd    []string
+	tagRemove []string
+	desc      string
+	descSet   bool
+
+	check      bool
+	health     bool
+	json       bool
+	expireDays int
+
 	skipConfirm bool
 
 	configMgr  kubeconfig.Manager
 	historyMgr history.Manager
 
+	healthCache *kubeconfig.HealthCache
+
 	cur     *kubeconfig.KubeConfig
 	curName string
 }
 
-func (o *Options) Validate(_ *cobra.Command, args []string) error {
+func (o *Options) Validate(c *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		o.name = args[0]
 	}
+	if len(args) > 1 {
+		o.target = args[1]
+	}
+
+	o.descSet = c.Flags().Changed("desc")
 
 	opts := []bool{
 		o.edit, o.delete, o.deleteAll, o.list, o.listHistory, o.unuse,
+		o.rename, o.copy, o.alias != "", o.isMetadata(), o.check, o.health,
 	}
-	var hasMode bool
+	var modeCount int
 	for _, opt := range opts {
 		if opt {
-			hasMode = true
-			continue
-		}
-		if hasMode && opt {
-			return errors.New("mode cannot duplicate")
+			modeCount++
 		}
 	}
+	if modeCount > 1 {
+		return errors.New("mode cannot duplicate")
+	}
+
+	if o.target != "" && !o.rename && !o.copy && o.alias != "add" {
+		return errors.New("the second arg can only be used with `--rename`, `--copy` or `--alias add`")
+	}
 
 	return nil
 }
@@ -97,9 +149,21 @@ func (o *Options) Run(cmdctx *cmd.Context) error {
 	case o.list:
 		return o.handleList()
 	case o.listHistory:
-		return o.handleListHistory()
+		return o.handleListHistory(cmdctx)
 	case o.unuse:
 		return o.handleUnuse(cmdctx)
+	case o.rename:
+		return o.handleRename()
+	case o.copy:
+		return o.handleCopy()
+	case o.alias != "":
+		return o.handleAlias()
+	case o.isMetadata():
+		return o.handleMetadata()
+	case o.check:
+		return o.handleCheck()
+	case o.health:
+		return o.handleHealth(cmdctx)
 	default:
 		return o.handleUse(cmdctx)
 	}
@@ -107,7 +171,7 @@ func (o *Options) Run(cmdctx *cmd.Context) error {
 
 func (o *Options) prepare(cmdctx cmd.Context) error {
 	cfg := cmdctx.Config
-	configMgr, err := kubeconfig.NewManager(cfg.KubeConfig.Root, cfg.KubeConfig.Alias)
+	configMgr, err := kubeconfig.NewManager(cfg)
 	if err != nil {
 		return err
 	}
@@ -124,6 +188,7 @@ func (o *Options) prepare(cmdctx cmd.Context) error {
 	}
 	o.configMgr = configMgr
 	o.historyMgr = histMgr
+	o.healthCache = kubeconfig.LoadHealthCache(cfg.RuntimeDir)
 
 	return nil
 }
@@ -158,6 +223,10 @@ func (o *Options) selectUse(cmdctx *cmd.Context) (*kubeconfig.KubeConfig, error)
 		return kc, nil
 	}
 
+	if strings.HasPrefix(o.name, "@") {
+		return o.jumpOne(strings.TrimPrefix(o.name, "@"))
+	}
+
 	if o.name != "" {
 		kc, ok := o.configMgr.Get(o.name)
 		if !ok {
@@ -193,9 +262,8 @@ func (o *Options) handleEdit(cmdctx *cmd.Context) error {
 			return err
 		}
 	} else {
-		path := kc.Path()
-		initData, err = os.ReadFile(path)
-		if err != nil && !os.IsNotExist(err) {
+		initData, err = o.configMgr.Read(kc.Name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return err
 		}
 	}
@@ -264,27 +332,41 @@ func (o *Options) selectDelete() (string, error) {
 }
 
 func (o *Options) handleList() error {
-	kc, err := o.selectGet()
-	if err != nil {
-		return err
-	}
-	if kc != nil {
-		fmt.Println(kc.String())
-		return nil
+	if o.name != "" || len(o.tags) == 0 {
+		kc, err := o.selectGet()
+		if err != nil {
+			return err
+		}
+		if kc != nil {
+			line := kc.String()
+			if mark := o.expiryMark(kc); mark != "" {
+				line = fmt.Sprintf("%s %s", line, mark)
+			}
+			fmt.Println(line)
+			return nil
+		}
 	}
 
-	kcs := o.configMgr.List()
+	kcs := o.listConfigs()
+	rows := make([][]string, 0, len(kcs))
 	for _, kc := range kcs {
-		line := kc.String()
+		name := kc.String()
 		if o.curName != "" && kc.Name == o.curName {
-			line = color.New(color.Bold).Sprintf("* %s", line)
+			name = fmt.Sprintf("* %s", name)
+		}
+		meta := o.configMgr.GetMetadata(kc.Name)
+		rows = append(rows, []string{name, meta.FormatTags(), meta.Description, o.expiryMark(kc)})
+	}
+	for i, line := range term.FormatTable(rows) {
+		if o.curName != "" && kcs[i].Name == o.curName {
+			line = color.New(color.Bold).Sprint(line)
 		}
 		fmt.Println(line)
 	}
 	return nil
 }
 
-func (o *Options) handleListHistory() error {
+func (o *Options) handleListHistory(cmdctx *cmd.Context) error {
 	records := o.historyMgr.List()
 
 	kc, err := o.selectGet()
@@ -302,10 +384,16 @@ func (o *Options) handleListHistory() error {
 	}
 
 	for _, record := range records {
-		if record.Namespace != "" {
+		// Switching kubeconfig records the default namespace, other namespaces
+		// are recorded by `kw ns`
+		if record.Namespace != "" && record.Namespace != cmdctx.Config.DefaultNamespace(record.Name) {
 			continue
 		}
-		fmt.Printf("[%s] %s\n", term.FormatTimestamp(record.Timestamp), record.Name)
+		name := record.Name
+		if record.Context != "" {
+			name = fmt.Sprintf("%s (context %s)", name, record.Context)
+		}
+		fmt.Printf("[%s] %s\n", term.FormatTimestamp(record.Timestamp), name)
 	}
 
 	return nil
@@ -323,19 +411,29 @@ func (o *Options) selectGet() (*kubeconfig.KubeConfig, error) {
 }
 
 func (o *Options) use(cmdctx *cmd.Context, kc *kubeconfig.KubeConfig) error {
-	term.PrintHint("Switch to kubeconfig %q", kc.Name)
-	src := kc.GenerateSource("")
-	err := source.Apply(cmdctx.Config, src)
+	ns := cmdctx.Config.DefaultNamespace(kc.Name)
+	if ns != "" {
+		term.PrintHint("Switch to kubeconfig %q, namespace %q", kc.Name, ns)
+	} else {
+		term.PrintHint("Switch to kubeconfig %q", kc.Name)
+	}
+	err := o.configMgr.Activate(kc)
+	if err != nil {
+		return err
+	}
+
+	src := kc.GenerateSource(ns, "")
+	err = source.Apply(cmdctx.Config, src)
 	if err != nil {
 		return err
 	}
 
-	o.historyMgr.Add(kc.Name, "")
+	o.historyMgr.Add(kc.Name, ns, "")
 	return o.historyMgr.Save()
 }
 
 func (o *Options) selectOne() (*kubeconfig.KubeConfig, error) {
-	kcs := o.configMgr.List()
+	kcs := o.listConfigs()
 	filtered := make([]*kubeconfig.KubeConfig, 0, len(kcs))
 	for _, kc := range kcs {
 		if o.curName != "" && kc.Name == o.curName {
@@ -344,14 +442,21 @@ func (o *Options) selectOne() (*kubeconfig.KubeConfig, error) {
 		filtered = append(filtered, kc)
 	}
 
-	items := make([]string, 0, len(filtered))
-	for _, kc := range filtered {
-		items = append(items, kc.Name)
+	if len(filtered) == 0 {
+		return nil, errors.New("no kubeconfig to select")
 	}
 
-	if len(items) == 0 {
-		return nil, errors.New("no kubeconfig to select")
+	scores := history.ConfigScores(o.historyMgr.List())
+	history.Sort(filtered, func(kc *kubeconfig.KubeConfig) string {
+		return kc.Name
+	}, scores)
+
+	rows := make([][]string, 0, len(filtered))
+	for _, kc := range filtered {
+		meta := o.configMgr.GetMetadata(kc.Name)
+		rows = append(rows, []string{kc.Name, meta.FormatTags(), meta.Description, o.healthMark(kc)})
 	}
+	items := term.FormatTable(rows)
 
 	idx, err := fzf.Search(items)
 	if err != nil {
@@ -361,16 +466,50 @@ func (o *Options) selectOne() (*kubeconfig.KubeConfig, error) {
 	return filtered[idx], nil
 }
 
+// jumpOne selects the kubeconfig with the highest frecency that fuzzy matches
+// the query, without fzf.
+func (o *Options) jumpOne(query string) (*kubeconfig.KubeConfig, error) {
+	if query == "" {
+		return nil, errors.New("query after '@' is required")
+	}
+
+	var names []string
+	for _, kc := range o.configMgr.List() {
+		if o.curName != "" && kc.Name == o.curName {
+			continue
+		}
+		names = append(names, kc.Name)
+	}
+
+	scores := history.ConfigScores(o.historyMgr.List())
+	name, ok := history.BestMatch(query, names, scores)
+	if !ok {
+		return nil, fmt.Errorf("no kubeconfig matches %q", query)
+	}
+
+	kc, _ := o.configMgr.Get(name)
+	return kc, nil
+}
+
 func (o *Options) handleUnuse(cmdctx *cmd.Context) error {
 	if o.curName == "" {
 		return errors.New("no current kubeconfig used, cannot unuse")
 	}
 
 	term.PrintHint("Unuse current kubeconfig %q", o.curName)
+	err := o.configMgr.Deactivate()
+	if err != nil {
+		return err
+	}
+
 	src := kubeconfig.UnsetSource()
 	return source.Apply(cmdctx.Config, src)
 }
 
+func (o *Options) isMetadata() bool {
+	return len(o.tagAdd) > 0 || len(o.tagRemove) > 0 || o.descSet
+}
+
 func (o *Options) handleDeleteAll() error {
 	_, ok := o.configMgr.Current()
 	if ok {
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/secret"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func newDoctor() *cobra.Command {
+	var opts DoctorOptions
+	c := &cobra.Command{
+		Use:   "doctor",
+		Short: "Find and fix insecure kubeconfig files",
+		Long: `Find and fix insecure kubeconfig files:
+
+- The files and directories that can be accessed by other users.
+- The plaintext files when encryption is enabled, such as the files written
+  before enabling encryption. They are encrypted in place.
+- The kubeconfigs shadowed by subcommands of "config", only warned.`,
+		Args: cobra.NoArgs,
+	}
+
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
+	return cmd.Build(c, &opts)
+}
+
+type DoctorOptions struct {
+	skipConfirm bool
+}
+
+type permissionIssue struct {
+	path string
+	mode os.FileMode
+	want os.FileMode
+}
+
+func (o *DoctorOptions) Validate(_ *cobra.Command, _ []string) error { return nil }
+
+func (o *DoctorOptions) Run(cmdctx *cmd.Context) error {
+	root := cmdctx.Config.KubeConfig.Root
+
+	var issues []*permissionIssue
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// The permission of symlink itself is always 0777 and meaningless,
+			// and chmod follows it to the target, which may be out of root
+			return nil
+		}
+
+		want := dirs.FilePerm
+		if info.IsDir() {
+			want = dirs.DirPerm
+		}
+		// Only the owner can access the credentials
+		mode := info.Mode().Perm()
+		if mode&0077 != 0 {
+			issues = append(issues, &permissionIssue{path: path, mode: mode, want: want})
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			term.PrintHint("Kubeconfig root %q does not exist, nothing to check", root)
+			return nil
+		}
+		return fmt.Errorf("check kubeconfig root: %w", err)
+	}
+
+	for _, name := range kubeconfig.ReservedNames {
+		_, err = os.Lstat(filepath.Join(root, name))
+		if err == nil {
+			fmt.Fprintln(os.Stderr, color.YellowString("Kubeconfig %q is shadowed by subcommand `config %s`, please rename the file", name, name))
+		}
+	}
+
+	if len(issues) == 0 {
+		term.PrintHint("All kubeconfig files have secure permissions")
+		return o.encryptPlaintext(cmdctx)
+	}
+
+	rows := make([][]string, 0, len(issues))
+	for _, issue := range issues {
+		rows = append(rows, []string{issue.path, issue.mode.String(), "->", issue.want.String()})
+	}
+	for _, line := range term.FormatTable(rows) {
+		fmt.Println(line)
+	}
+
+	err = term.Confirm(o.skipConfirm, "Fix permissions of %d file(s)", len(issues))
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		err = os.Chmod(issue.path, issue.want)
+		if err != nil {
+			return fmt.Errorf("fix permission of %q: %w", issue.path, err)
+		}
+	}
+
+	term.PrintHint("Fixed permissions of %d file(s)", len(issues))
+	return o.encryptPlaintext(cmdctx)
+}
+
+// encryptPlaintext encrypts the plaintext kubeconfig files when encryption is
+// enabled.
+func (o *DoctorOptions) encryptPlaintext(cmdctx *cmd.Context) error {
+	if !cmdctx.Config.KubeConfig.Encryption.Enable {
+		return nil
+	}
+
+	mgr, err := kubeconfig.NewManager(cmdctx.Config)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, kc := range mgr.List() {
+		if kc.Alias != "" {
+			continue
+		}
+		data, err := os.ReadFile(kc.Path())
+		if err != nil {
+			return fmt.Errorf("read kubeconfig %q: %w", kc.Name, err)
+		}
+		if !secret.IsEncrypted(data) {
+			names = append(names, kc.Name)
+		}
+	}
+	if len(names) == 0 {
+		term.PrintHint("All kubeconfig files are encrypted")
+		return nil
+	}
+
+	fmt.Fprintln(os.Stderr, color.YellowString("Found %d plaintext kubeconfig file(s):", len(names)))
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	err = term.Confirm(o.skipConfirm, "Encrypt %d file(s)", len(names))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		data, err := mgr.Read(name)
+		if err != nil {
+			return err
+		}
+		// Put encrypts data when encryption is enabled
+		_, err = mgr.Put(name, data)
+		if err != nil {
+			return fmt.Errorf("encrypt kubeconfig %q: %w", name, err)
+		}
+	}
+
+	term.PrintHint("Encrypted %d file(s)", len(names))
+	return nil
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func newExport() *cobra.Command {
+	var opts ExportOptions
+	c := &cobra.Command{
+		Use:   "export [NAME...]",
+		Short: "Merge kubeconfig files into one file",
+		Long: `Merge kubeconfig files into one file, for the tools that need a single
+kubeconfig file.
+
+The contexts, clusters and users are renamed by the kubeconfig names to make
+them unique. Without NAME and --tag, all kubeconfig files are exported.`,
+
+		ValidArgsFunction: CompletionFunc,
+	}
+
+	c.Flags().StringVarP(&opts.output, "output", "o", "-", "output file path, '-' means stdout")
+	c.Flags().StringSliceVarP(&opts.tags, "tag", "t", nil, "select kubeconfig files by tags, in 'key=value' or 'key' format")
+	c.Flags().StringVarP(&opts.current, "current", "c", "", "the kubeconfig to use as current-context, default is the current kubeconfig or the first one")
+	c.Flags().BoolVarP(&opts.flatten, "flatten", "", false, "embed external cert files into data")
+
+	return cmd.Build(c, &opts)
+}
+
+type ExportOptions struct {
+	names []string
+
+	output  string
+	tags    []string
+	current string
+	flatten bool
+}
+
+func (o *ExportOptions) Validate(_ *cobra.Command, args []string) error {
+	o.names = args
+	if len(o.names) > 0 && len(o.tags) > 0 {
+		return errors.New("names and `--tag` cannot be used together")
+	}
+	return nil
+}
+
+func (o *ExportOptions) Run(cmdctx *cmd.Context) error {
+	configMgr, err := kubeconfig.NewManager(cmdctx.Config)
+	if err != nil {
+		return err
+	}
+
+	kcs, err := o.selectConfigs(configMgr)
+	if err != nil {
+		return err
+	}
+
+	items := make([]*kubeconfig.MergeItem, 0, len(kcs))
+	for _, kc := range kcs {
+		data, err := configMgr.Read(kc.Name)
+		if err != nil {
+			return err
+		}
+		items = append(items, &kubeconfig.MergeItem{
+			Name: kc.Name,
+			Data: data,
+			Dir:  filepath.Dir(kc.Path()),
+		})
+	}
+
+	file, currentContexts, err := kubeconfig.Merge(items, o.flatten)
+	if err != nil {
+		return err
+	}
+
+	currentName := o.current
+	if currentName == "" {
+		cur, ok := configMgr.Current()
+		if ok {
+			currentName = cur.Name
+		}
+	}
+	currentContext, ok := currentContexts[currentName]
+	if !ok {
+		if o.current != "" {
+			return fmt.Errorf("the current kubeconfig %q is not exported", o.current)
+		}
+		currentContext = currentContexts[kcs[0].Name]
+	}
+	file.CurrentContext = currentContext
+
+	data, err := file.Encode()
+	if err != nil {
+		return err
+	}
+
+	if o.output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	err = dirs.EnsureCreate(filepath.Dir(o.output))
+	if err != nil {
+		return fmt.Errorf("ensure output dir: %w", err)
+	}
+	err = atomicfile.Write(o.output, data, dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
+
+	term.PrintHint("Export %d kubeconfig(s) to %q", len(kcs), o.output)
+	return nil
+}
+
+func (o *ExportOptions) selectConfigs(configMgr kubeconfig.Manager) ([]*kubeconfig.KubeConfig, error) {
+	var kcs []*kubeconfig.KubeConfig
+	if len(o.names) > 0 {
+		for _, name := range o.names {
+			kc, ok := configMgr.Get(name)
+			if !ok {
+				return nil, fmt.Errorf("cannot find kubeconfig %q to export", name)
+			}
+			kcs = append(kcs, kc)
+		}
+		return kcs, nil
+	}
+
+	for _, kc := range configMgr.List() {
+		if kc.Alias != "" {
+			// Avoid exporting the same kubeconfig twice
+			continue
+		}
+		if !configMgr.GetMetadata(kc.Name).Match(o.tags) {
+			continue
+		}
+		kcs = append(kcs, kc)
+	}
+	if len(kcs) == 0 {
+		return nil, errors.New("no kubeconfig to export")
+	}
+	return kcs, nil
+}
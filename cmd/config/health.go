@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/fioncat/kubewrap/pkg/term"
+)
+
+type healthResult struct {
+	Name string `json:"name"`
+
+	*kubeconfig.Health
+}
+
+func (o *Options) handleHealth(cmdctx *cmd.Context) error {
+	var kcs []*kubeconfig.KubeConfig
+	if o.name != "" {
+		kc, ok := o.configMgr.Get(o.name)
+		if !ok {
+			return fmt.Errorf("cannot find kubeconfig %q to check health", o.name)
+		}
+		kcs = []*kubeconfig.KubeConfig{kc}
+	} else {
+		kcs = o.listConfigs()
+	}
+
+	// The context selected by session only belongs to the current kubeconfig,
+	// so don't use it to probe others
+	cfg := cmdctx.Config
+	k := kubectl.NewCommand(cfg.Kubectl.Name, cfg.Kubectl.Args)
+	healths := kubeconfig.ProbeAll(o.configMgr, k, kcs)
+
+	results := make([]*healthResult, 0, len(kcs))
+	for _, kc := range kcs {
+		health := healths[kc.Name]
+		o.healthCache.Put(kc.Name, health)
+		results = append(results, &healthResult{Name: kc.Name, Health: health})
+	}
+	err := o.healthCache.Save()
+	if err != nil {
+		return fmt.Errorf("save health cache: %w", err)
+	}
+
+	if o.json {
+		return term.PrintJson(results)
+	}
+
+	rows := [][]string{{"NAME", "STATUS", "VERSION", "ERROR"}}
+	colors := []*color.Color{color.New(color.Bold)}
+	for _, result := range results {
+		rows = append(rows, []string{result.Name, result.Status(), result.Version, result.Error})
+		switch result.Status() {
+		case "ok":
+			colors = append(colors, color.New(color.FgGreen))
+		case "auth-failed", "expired":
+			colors = append(colors, color.New(color.FgYellow))
+		default:
+			colors = append(colors, color.New(color.FgRed))
+		}
+	}
+
+	for i, line := range term.FormatTable(rows) {
+		fmt.Println(colors[i].Sprint(line))
+	}
+	return nil
+}
+
+// healthMark returns the cached health status of kubeconfig, empty string if
+// it was not checked recently.
+func (o *Options) healthMark(kc *kubeconfig.KubeConfig) string {
+	health, ok := o.healthCache.Get(kc.Name)
+	if !ok {
+		return ""
+	}
+	if health.Status() == "ok" && health.Version != "" {
+		return fmt.Sprintf("[ok %s]", health.Version)
+	}
+	return fmt.Sprintf("[%s]", health.Status())
+}
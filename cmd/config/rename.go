@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fioncat/kubewrap/pkg/term"
+)
+
+func (o *Options) handleRename() error {
+	if o.name == "" || o.target == "" {
+		return errors.New("rename requires old and new names")
+	}
+
+	_, err := o.configMgr.Rename(o.name, o.target)
+	if err != nil {
+		return err
+	}
+
+	o.historyMgr.Rename(o.name, o.target)
+	err = o.historyMgr.Save()
+	if err != nil {
+		return err
+	}
+
+	term.PrintHint("Renamed kubeconfig %q to %q", o.name, o.target)
+	return nil
+}
+
+func (o *Options) handleCopy() error {
+	if o.name == "" || o.target == "" {
+		return errors.New("copy requires source and destination names")
+	}
+
+	_, err := o.configMgr.Copy(o.name, o.target)
+	if err != nil {
+		return err
+	}
+
+	term.PrintHint("Copied kubeconfig %q to %q", o.name, o.target)
+	return nil
+}
+
+func (o *Options) handleAlias() error {
+	switch o.alias {
+	case "add":
+		if o.name == "" || o.target == "" {
+			return errors.New("alias add requires alias name and target")
+		}
+		err := o.configMgr.AddAlias(o.name, o.target)
+		if err != nil {
+			return err
+		}
+		term.PrintHint("Added alias %q to %q", o.name, o.target)
+		return nil
+
+	case "remove":
+		if o.name == "" {
+			return errors.New("alias remove requires alias name")
+		}
+		err := o.configMgr.RemoveAlias(o.name)
+		if err != nil {
+			return err
+		}
+		o.historyMgr.DeleteByName(o.name)
+		err = o.historyMgr.Save()
+		if err != nil {
+			return err
+		}
+		term.PrintHint("Removed alias %q", o.name)
+		return nil
+
+	case "list":
+		for _, kc := range o.configMgr.ListAliases() {
+			fmt.Println(kc.String())
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown alias action %q, should be one of: add|remove|list", o.alias)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/history"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func newSync() *cobra.Command {
+	var opts SyncOptions
+	c := &cobra.Command{
+		Use:   "sync [SOURCE...]",
+		Short: "Pull kubeconfig files from the sources in config",
+		Long: `Pull kubeconfig files from the sources in config.
+
+Each source is synced into the subtree '<kubeconfig.root>/<source>'. Only the
+kubeconfig files created by sync are updated or removed, the manually created
+ones are never touched.`,
+	}
+
+	return cmd.Build(c, &opts)
+}
+
+type SyncOptions struct {
+	names []string
+}
+
+func (o *SyncOptions) Validate(_ *cobra.Command, args []string) error {
+	o.names = args
+	return nil
+}
+
+func (o *SyncOptions) Run(cmdctx *cmd.Context) error {
+	cfg := cmdctx.Config
+	sources, err := o.selectSources(cfg.KubeConfig.Sync.Sources)
+	if err != nil {
+		return err
+	}
+
+	configMgr, err := kubeconfig.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+	historyMgr, err := history.NewManager(cfg.History.Path, cfg.History.Max)
+	if err != nil {
+		return err
+	}
+
+	for _, src := range sources {
+		term.PrintHint("Sync source %q", src.Name)
+		result, err := kubeconfig.Sync(configMgr, cfg.KubeConfig.Sync.StatePath, src)
+		if err != nil {
+			return err
+		}
+		for _, name := range result.Removed {
+			historyMgr.DeleteByName(name)
+		}
+		printSyncResult(result)
+	}
+
+	return historyMgr.Save()
+}
+
+func (o *SyncOptions) selectSources(sources []*config.SyncSource) ([]*config.SyncSource, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no sync source in config, please add `kubeconfig.sync.sources` first")
+	}
+	if len(o.names) == 0 {
+		return sources, nil
+	}
+
+	selected := make([]*config.SyncSource, 0, len(o.names))
+	for _, name := range o.names {
+		var found *config.SyncSource
+		for _, src := range sources {
+			if src.Name == name {
+				found = src
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("cannot find sync source %q in config", name)
+		}
+		selected = append(selected, found)
+	}
+	return selected, nil
+}
+
+func printSyncResult(result *kubeconfig.SyncResult) {
+	for _, name := range result.Added {
+		color.Green("  + %s", name)
+	}
+	for _, name := range result.Changed {
+		color.Yellow("  ~ %s", name)
+	}
+	for _, name := range result.Removed {
+		color.Red("  - %s", name)
+	}
+	for _, skip := range result.Skipped {
+		fmt.Println(color.New(color.Faint).Sprintf("  ! %s: %s", skip.Name, skip.Reason))
+	}
+	fmt.Printf("%d added, %d changed, %d removed, %d skipped\n",
+		len(result.Added), len(result.Changed), len(result.Removed), len(result.Skipped))
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/term"
+)
+
+func (o *Options) handleMetadata() error {
+	name := o.name
+	if name == "" {
+		name = o.curName
+	}
+	if name == "" {
+		return errors.New("no kubeconfig to update metadata, please specify a name")
+	}
+
+	meta := o.configMgr.GetMetadata(name)
+	newMeta := &kubeconfig.Metadata{
+		Tags:        make(map[string]string, len(meta.Tags)),
+		Description: meta.Description,
+	}
+	for key, value := range meta.Tags {
+		newMeta.Tags[key] = value
+	}
+
+	addTags, err := kubeconfig.ParseTags(o.tagAdd)
+	if err != nil {
+		return err
+	}
+	for key, value := range addTags {
+		newMeta.Tags[key] = value
+	}
+	for _, key := range o.tagRemove {
+		delete(newMeta.Tags, key)
+	}
+	if o.descSet {
+		newMeta.Description = o.desc
+	}
+
+	err = o.configMgr.PutMetadata(name, newMeta)
+	if err != nil {
+		return err
+	}
+
+	term.PrintHint("Update metadata of kubeconfig %q", name)
+	return nil
+}
+
+// listConfigs returns the kubeconfigs that match the tag filters.
+func (o *Options) listConfigs() []*kubeconfig.KubeConfig {
+	kcs := o.configMgr.List()
+	if len(o.tags) == 0 {
+		return kcs
+	}
+
+	filtered := make([]*kubeconfig.KubeConfig, 0, len(kcs))
+	for _, kc := range kcs {
+		if o.configMgr.GetMetadata(kc.Name).Match(o.tags) {
+			filtered = append(filtered, kc)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,38 @@
+package ctx
+
+import (
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+func CompletionFunc(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	mgr := cmd.GetCompleteKubeconfigManager(c)
+	if mgr == nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	cur, ok := mgr.Current()
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	file, err := readFile(mgr, cur.Name)
+	if err != nil {
+		cmd.WriteCompleteLogs("read current kubeconfig: %v", err)
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	curContext := kubeconfig.GetCurrentContext()
+	items := make([]string, 0, len(file.Contexts))
+	for _, ctx := range file.Contexts {
+		if ctx.Name == curContext {
+			continue
+		}
+		items = append(items, ctx.Name)
+	}
+	return items, cobra.ShellCompDirectiveNoFileComp
+}
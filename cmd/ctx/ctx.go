@@ -0,0 +1,164 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/fzf"
+	"github.com/fioncat/kubewrap/pkg/history"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/source"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "ctx [NAME]",
+		Short: "Switch to context inside current kubeconfig",
+		Long: `Switch to context inside current kubeconfig.
+
+The context is selected by the session only, the kubeconfig file is not
+modified.`,
+		Args: cobra.MaximumNArgs(1),
+
+		ValidArgsFunction: CompletionFunc,
+	}
+
+	c.Flags().BoolVarP(&opts.unuse, "unuse", "u", false, "unuse context, back to the current-context in kubeconfig file")
+	c.Flags().BoolVarP(&opts.list, "list", "l", false, "list contexts")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	name string
+
+	unuse bool
+	list  bool
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		o.name = args[0]
+	}
+	if o.unuse && o.list {
+		return errors.New("mode cannot duplicate")
+	}
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	cfg := cmdctx.Config
+	configMgr, err := kubeconfig.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+
+	cur, ok := configMgr.Current()
+	if !ok {
+		return errors.New("no kubeconfig selected, cannot perform ctx operations, please select one first")
+	}
+
+	file, err := readFile(configMgr, cur.Name)
+	if err != nil {
+		return err
+	}
+	curContext := kubeconfig.GetCurrentContext()
+	if curContext == "" {
+		curContext = file.CurrentContext
+	}
+
+	if o.list {
+		rows := [][]string{{"NAME", "CLUSTER", "USER", "NAMESPACE"}}
+		for _, ctx := range file.Contexts {
+			rows = append(rows, contextRow(ctx, curContext))
+		}
+		for i, line := range term.FormatTable(rows) {
+			if i == 0 || file.Contexts[i-1].Name == curContext {
+				line = color.New(color.Bold).Sprint(line)
+			}
+			fmt.Println(line)
+		}
+		return nil
+	}
+
+	if o.unuse {
+		if kubeconfig.GetCurrentContext() == "" {
+			return errors.New("no context selected by session, cannot unuse")
+		}
+		term.PrintHint("Unuse context, back to %q", file.CurrentContext)
+		return source.Apply(cfg, cur.GenerateSource("", ""))
+	}
+
+	name, err := o.selectContext(file, curContext)
+	if err != nil {
+		return err
+	}
+
+	// The namespace may not exist in the new context, so reset it
+	term.PrintHint("Switch to context %q", name)
+	err = source.Apply(cfg, cur.GenerateSource("", name))
+	if err != nil {
+		return err
+	}
+
+	histMgr, err := history.NewManager(cfg.History.Path, cfg.History.Max)
+	if err != nil {
+		return err
+	}
+	histMgr.Add(cur.Name, "", name)
+	return histMgr.Save()
+}
+
+func (o *Options) selectContext(file *kubeconfig.File, curContext string) (string, error) {
+	if o.name != "" {
+		if file.GetContext(o.name) == nil {
+			return "", fmt.Errorf("cannot find context %q in current kubeconfig", o.name)
+		}
+		return o.name, nil
+	}
+
+	contexts := make([]*kubeconfig.NamedContext, 0, len(file.Contexts))
+	for _, ctx := range file.Contexts {
+		if ctx.Name == curContext {
+			continue
+		}
+		contexts = append(contexts, ctx)
+	}
+	if len(contexts) == 0 {
+		return "", errors.New("no other context to select")
+	}
+
+	rows := make([][]string, 0, len(contexts))
+	for _, ctx := range contexts {
+		rows = append(rows, contextRow(ctx, curContext))
+	}
+	idx, err := fzf.Search(term.FormatTable(rows))
+	if err != nil {
+		return "", err
+	}
+	return contexts[idx].Name, nil
+}
+
+func contextRow(ctx *kubeconfig.NamedContext, curContext string) []string {
+	name := ctx.Name
+	if name == curContext {
+		name = fmt.Sprintf("* %s", name)
+	}
+	if ctx.Context == nil {
+		return []string{name, "", "", ""}
+	}
+	return []string{name, ctx.Context.Cluster, ctx.Context.User, ctx.Context.Namespace}
+}
+
+func readFile(configMgr kubeconfig.Manager, name string) (*kubeconfig.File, error) {
+	data, err := configMgr.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return kubeconfig.ParseFile(data)
+}
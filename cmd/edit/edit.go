@@ -0,0 +1,204 @@
+package edit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/diff"
+	"github.com/fioncat/kubewrap/pkg/edit"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "edit <QUERY>",
+		Short: "Edit a resource with the configured editor",
+		Args:  cobra.ExactArgs(1),
+
+		ValidArgsFunction: CompletionFunc,
+	}
+
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	query string
+
+	skipConfirm bool
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	o.query = args[0]
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	r, err := cmd.SelectResource(cmdctx.Kubectl, o.query)
+	if err != nil {
+		return err
+	}
+
+	// When conflict, the editor is reopened with the changes of user, instead
+	// of the latest resource.
+	var edited []byte
+	for {
+		data, err := cmdctx.Kubectl.GetResource(r)
+		if err != nil {
+			return err
+		}
+		version, err := getResourceVersion(data)
+		if err != nil {
+			return err
+		}
+		data, err = kubectl.CleanResource(data)
+		if err != nil {
+			return err
+		}
+		current, err := yaml.JSONToYAML(data)
+		if err != nil {
+			return fmt.Errorf("convert resource to yaml: %w", err)
+		}
+
+		initData := current
+		if edited != nil {
+			initData = edited
+		}
+		newEdited, err := editResource(cmdctx.Config, initData, version)
+		if err != nil {
+			if edited == nil || !errors.Is(err, edit.ErrNotChanged) {
+				return err
+			}
+			// The user accepted the previous changes without modification
+			newEdited = edited
+		}
+		edited = newEdited
+
+		result, err := diff.Diff("current", current, "edited", edited)
+		if err != nil {
+			return err
+		}
+		if result == "" {
+			term.PrintHint("No changes to %v", r)
+			return nil
+		}
+		diff.Print(result)
+
+		err = term.Confirm(o.skipConfirm, "Apply changes to %v", r)
+		if err != nil {
+			return err
+		}
+
+		data, err = parseEdited(edited, version)
+		if err != nil {
+			return err
+		}
+
+		err = cmdctx.Kubectl.Replace(data)
+		if err == nil {
+			term.PrintHint("Applied changes to %v", r)
+			return nil
+		}
+		if !kubectl.IsConflict(err) {
+			return err
+		}
+
+		term.PrintHint("%v was modified by others, reopen editor with your changes based on the latest version", r)
+	}
+}
+
+// editResource opens the editor until the edited resource is valid. Like
+// `kubectl edit`, the editor is reopened with the error as a comment header. If
+// the user quits without fixing it, the edits are kept in a file.
+func editResource(cfg *config.Config, initData []byte, version string) ([]byte, error) {
+	data := initData
+	var header []byte
+	for {
+		edited, err := edit.Edit(cfg, data)
+		if err != nil {
+			if header == nil || !errors.Is(err, edit.ErrNotChanged) {
+				return nil, err
+			}
+			invalid := bytes.TrimPrefix(data, header)
+			path, keepErr := edit.Keep(cfg, invalid)
+			if keepErr != nil {
+				return nil, fmt.Errorf("edit canceled, and failed to keep your changes: %w", keepErr)
+			}
+			return nil, fmt.Errorf("edit canceled, your changes are kept in %q", path)
+		}
+		if header != nil {
+			edited = bytes.TrimPrefix(edited, header)
+		}
+
+		_, err = parseEdited(edited, version)
+		if err == nil {
+			return edited, nil
+		}
+
+		header = errorHeader(err)
+		data = append(header, edited...)
+	}
+}
+
+// errorHeader returns the comment lines to show the error in editor, they are
+// ignored by yaml.
+func errorHeader(err error) []byte {
+	var sb strings.Builder
+	sb.WriteString("# The edited resource is invalid, please fix it or quit without\n")
+	sb.WriteString("# saving to cancel:\n")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		sb.WriteString("#   " + line + "\n")
+	}
+	sb.WriteString("#\n")
+	return []byte(sb.String())
+}
+
+// parseEdited converts the edited yaml to json with resourceVersion.
+func parseEdited(edited []byte, version string) ([]byte, error) {
+	data, err := yaml.YAMLToJSON(edited)
+	if err != nil {
+		return nil, fmt.Errorf("parse edited yaml: %w", err)
+	}
+	return setResourceVersion(data, version)
+}
+
+func getResourceVersion(data []byte) (string, error) {
+	var obj struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+	}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return "", fmt.Errorf("decode resource: %w", err)
+	}
+	return obj.Metadata.ResourceVersion, nil
+}
+
+// setResourceVersion adds resourceVersion back to the cleaned resource, so that
+// the server can detect conflict when replacing.
+func setResourceVersion(data []byte, version string) ([]byte, error) {
+	var obj map[string]any
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("decode edited resource: %w", err)
+	}
+
+	meta, ok := obj["metadata"].(map[string]any)
+	if !ok {
+		return nil, errors.New("edited resource has no metadata")
+	}
+	meta["resourceVersion"] = version
+
+	return json.Marshal(obj)
+}
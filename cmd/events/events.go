@@ -0,0 +1,221 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+const watchInterval = time.Second * 2
+
+// The resource types owned by each kind, used to collect events of the whole
+// workload.
+var ownedResourceTypes = map[string][]string{
+	"Deployment":  {"replicasets"},
+	"CronJob":     {"jobs"},
+	"ReplicaSet":  {"pods"},
+	"StatefulSet": {"pods"},
+	"DaemonSet":   {"pods"},
+	"Job":         {"pods"},
+}
+
+// ownedKinds are the kinds that can be owned by others.
+var ownedKinds = map[string]bool{
+	"ReplicaSet": true,
+	"Job":        true,
+	"Pod":        true,
+}
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "events [QUERY]",
+		Short: "Show events of a resource and the resources it owns",
+		Args:  cobra.MaximumNArgs(1),
+
+		ValidArgsFunction: CompletionFunc,
+	}
+
+	c.Flags().BoolVarP(&opts.watch, "watch", "w", false, "watch new events")
+	c.Flags().BoolVarP(&opts.warningsOnly, "warnings-only", "W", false, "only show warning events")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	query string
+
+	watch        bool
+	warningsOnly bool
+}
+
+type objectKey struct {
+	kind string
+	name string
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		o.query = args[0]
+	}
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	k := cmdctx.Kubectl
+
+	namespace := cmd.GetCurrentNamespace()
+	var (
+		r       *kubectl.Resource
+		objects map[objectKey]struct{}
+	)
+	if o.query != "" {
+		var err error
+		r, err = cmd.SelectResource(k, o.query)
+		if err != nil {
+			return err
+		}
+		namespace = r.Namespace
+		objects, err = collectObjects(k, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Map event uid to its count, to find out the updated events in watch mode
+	seen := make(map[string]int)
+	// The objects not owned by the resource after refreshing, to avoid
+	// refreshing again for them
+	unowned := make(map[objectKey]struct{})
+	for {
+		events, err := k.ListEvents(namespace)
+		if err != nil {
+			return err
+		}
+
+		// New objects can be created after collecting, such as the pods of a
+		// rollout, refresh the owned objects when meeting unknown ones
+		if objects != nil && hasUnknownObject(events, objects, unowned) {
+			objects, err = collectObjects(k, r)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				key := objectKey{kind: event.ObjectKind, name: event.ObjectName}
+				if _, ok := objects[key]; !ok {
+					unowned[key] = struct{}{}
+				}
+			}
+		}
+		events = o.filterEvents(events, objects)
+
+		var rows [][]string
+		var types []string
+		for _, event := range events {
+			if count, ok := seen[event.UID]; ok && count == event.Count {
+				continue
+			}
+			seen[event.UID] = event.Count
+			rows = append(rows, []string{
+				term.FormatAge(event.LastTime),
+				event.Type,
+				fmt.Sprintf("%s/%s", event.ObjectKind, event.ObjectName),
+				event.Reason,
+				strconv.Itoa(event.Count),
+				event.Message,
+			})
+			types = append(types, event.Type)
+		}
+
+		for i, line := range term.FormatTable(rows) {
+			switch types[i] {
+			case "Warning":
+				line = color.YellowString(line)
+			case "Normal":
+				line = color.GreenString(line)
+			}
+			fmt.Println(line)
+		}
+
+		if !o.watch {
+			if len(rows) == 0 {
+				term.PrintHint("No events found")
+			}
+			return nil
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// hasUnknownObject reports whether there are events of the kinds that can be
+// owned, but the objects are neither collected nor known to be unowned.
+func hasUnknownObject(events []*kubectl.Event, objects, unowned map[objectKey]struct{}) bool {
+	for _, event := range events {
+		if !ownedKinds[event.ObjectKind] {
+			continue
+		}
+		key := objectKey{kind: event.ObjectKind, name: event.ObjectName}
+		if _, ok := objects[key]; ok {
+			continue
+		}
+		if _, ok := unowned[key]; ok {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (o *Options) filterEvents(events []*kubectl.Event, objects map[objectKey]struct{}) []*kubectl.Event {
+	filtered := make([]*kubectl.Event, 0, len(events))
+	for _, event := range events {
+		if o.warningsOnly && event.Type != "Warning" {
+			continue
+		}
+		if objects != nil {
+			key := objectKey{kind: event.ObjectKind, name: event.ObjectName}
+			if _, ok := objects[key]; !ok {
+				continue
+			}
+		}
+		filtered = append(filtered, event)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].LastTime.Before(filtered[j].LastTime)
+	})
+	return filtered
+}
+
+// collectObjects returns the resource and all resources it owns (recursively),
+// such as the ReplicaSets and Pods of a Deployment.
+func collectObjects(k kubectl.Kubectl, r *kubectl.Resource) (map[objectKey]struct{}, error) {
+	objects := make(map[objectKey]struct{})
+	queue := []*kubectl.Resource{r}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		kind := kubectl.ResourceKind(cur.Type)
+		if kind == "" {
+			return nil, fmt.Errorf("unsupported resource type %q", cur.Type)
+		}
+		objects[objectKey{kind: kind, name: cur.Name}] = struct{}{}
+
+		for _, resourceType := range ownedResourceTypes[kind] {
+			owned, err := k.ListOwnedResources(cur, resourceType)
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, owned...)
+		}
+	}
+	return objects, nil
+}
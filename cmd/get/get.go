@@ -0,0 +1,92 @@
+package get
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "get <QUERY>",
+		Short: "Get a resource",
+		Args:  cobra.ExactArgs(1),
+
+		ValidArgsFunction: CompletionFunc,
+	}
+
+	c.Flags().StringVarP(&opts.output, "output", "o", "", "output format, one of: yaml|json|wide")
+	c.Flags().BoolVarP(&opts.clean, "clean", "c", false, "remove server-managed fields (status, managedFields, uid, etc.) in yaml or json output")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	query string
+
+	output string
+	clean  bool
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	o.query = args[0]
+
+	switch o.output {
+	case "", "wide":
+		if o.clean {
+			return errors.New("`--clean` can only be used with yaml or json output")
+		}
+	case "yaml", "json":
+	default:
+		return fmt.Errorf("unknown output format %q, should be one of: yaml|json|wide", o.output)
+	}
+
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	r, err := cmd.SelectResource(cmdctx.Kubectl, o.query)
+	if err != nil {
+		return err
+	}
+
+	if !o.clean {
+		return cmdctx.Kubectl.PrintResource(r, o.output)
+	}
+
+	data, err := cmdctx.Kubectl.GetResource(r)
+	if err != nil {
+		return err
+	}
+	data, err = kubectl.CleanResource(data)
+	if err != nil {
+		return err
+	}
+
+	switch o.output {
+	case "yaml":
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return fmt.Errorf("convert resource to yaml: %w", err)
+		}
+
+	case "json":
+		var buf bytes.Buffer
+		err = json.Indent(&buf, data, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		data = buf.Bytes()
+	}
+
+	fmt.Print(string(data))
+	return nil
+}
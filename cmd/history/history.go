@@ -0,0 +1,397 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/fzf"
+	"github.com/fioncat/kubewrap/pkg/history"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/source"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "history",
+		Short: "Browse history, jump back to a past kubeconfig and namespace",
+		Long: `Browse history, jump back to a past kubeconfig and namespace.
+
+Without mode flags, select a past state (kubeconfig, namespace and context)
+through fzf and switch to it.`,
+		Args: cobra.NoArgs,
+	}
+
+	c.Flags().BoolVarP(&opts.list, "list", "l", false, "list history records")
+	c.Flags().BoolVarP(&opts.stats, "stats", "s", false, "show the most used kubeconfigs and namespaces")
+	c.Flags().BoolVarP(&opts.prune, "prune", "", false, "remove records of deleted kubeconfigs, and the records older than --older-than")
+
+	c.Flags().StringVarP(&opts.since, "since", "", "", "only show records newer than this duration, for example '2h' or '7d'")
+	c.Flags().StringVarP(&opts.until, "until", "", "", "only show records older than this duration")
+	c.Flags().StringVarP(&opts.name, "name", "", "", "only show records of kubeconfigs matching this glob pattern")
+	c.Flags().IntVarP(&opts.top, "top", "", 10, "the number of items in stats")
+	c.Flags().StringVarP(&opts.olderThan, "older-than", "", "", "prune the records older than this duration")
+
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	list  bool
+	stats bool
+	prune bool
+
+	since     string
+	until     string
+	name      string
+	top       int
+	olderThan string
+
+	skipConfirm bool
+
+	sinceTime     time.Time
+	untilTime     time.Time
+	olderThanTime time.Time
+
+	configMgr  kubeconfig.Manager
+	historyMgr history.Manager
+}
+
+func (o *Options) Validate(_ *cobra.Command, _ []string) error {
+	var modeCount int
+	for _, mode := range []bool{o.list, o.stats, o.prune} {
+		if mode {
+			modeCount++
+		}
+	}
+	if modeCount > 1 {
+		return errors.New("mode cannot duplicate")
+	}
+
+	if o.olderThan != "" && !o.prune {
+		return errors.New("`--older-than` can only be used with `--prune`")
+	}
+	if o.prune && (o.since != "" || o.until != "" || o.name != "") {
+		return errors.New("filters cannot be used with `--prune`")
+	}
+
+	now := time.Now()
+	for _, item := range []struct {
+		value string
+		time  *time.Time
+	}{
+		{o.since, &o.sinceTime},
+		{o.until, &o.untilTime},
+		{o.olderThan, &o.olderThanTime},
+	} {
+		if item.value == "" {
+			continue
+		}
+		d, err := term.ParseDuration(item.value)
+		if err != nil {
+			return err
+		}
+		*item.time = now.Add(-d)
+	}
+
+	if o.name != "" {
+		_, err := filepath.Match(o.name, "")
+		if err != nil {
+			return fmt.Errorf("invalid name pattern %q: %w", o.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	cfg := cmdctx.Config
+	var err error
+	o.configMgr, err = kubeconfig.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+	o.historyMgr, err = history.NewManager(cfg.History.Path, cfg.History.Max)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case o.list:
+		return o.handleList()
+	case o.stats:
+		return o.handleStats()
+	case o.prune:
+		return o.handlePrune()
+	default:
+		return o.handleJump(cmdctx)
+	}
+}
+
+func (o *Options) handleList() error {
+	records, err := o.filterAllRecords()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"TIME", "KUBECONFIG", "NAMESPACE", "CONTEXT"}}
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		rows = append(rows, []string{
+			term.FormatTimestamp(record.Timestamp),
+			record.Name,
+			record.Namespace,
+			record.Context,
+		})
+	}
+	for i, line := range term.FormatTable(rows) {
+		if i == 0 {
+			line = color.New(color.Bold).Sprint(line)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+type usage struct {
+	key   string
+	count int
+	last  int64
+}
+
+func (o *Options) handleStats() error {
+	records, err := o.filterAllRecords()
+	if err != nil {
+		return err
+	}
+
+	configUsages := make(map[string]*usage)
+	nsUsages := make(map[string]*usage)
+	for _, record := range records {
+		countUsage(configUsages, record.Name, record.Timestamp)
+		if record.Namespace != "" {
+			countUsage(nsUsages, fmt.Sprintf("%s/%s", record.Name, record.Namespace), record.Timestamp)
+		}
+	}
+
+	o.printUsages("KUBECONFIG", configUsages)
+	fmt.Println()
+	o.printUsages("NAMESPACE", nsUsages)
+	return nil
+}
+
+func countUsage(usages map[string]*usage, key string, ts int64) {
+	u, ok := usages[key]
+	if !ok {
+		u = &usage{key: key}
+		usages[key] = u
+	}
+	u.count++
+	if ts > u.last {
+		u.last = ts
+	}
+}
+
+func (o *Options) printUsages(title string, usages map[string]*usage) {
+	list := make([]*usage, 0, len(usages))
+	for _, u := range usages {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].count != list[j].count {
+			return list[i].count > list[j].count
+		}
+		return list[i].last > list[j].last
+	})
+	if o.top > 0 && len(list) > o.top {
+		list = list[:o.top]
+	}
+
+	rows := [][]string{{title, "COUNT", "LAST USED"}}
+	for _, u := range list {
+		rows = append(rows, []string{
+			u.key,
+			strconv.Itoa(u.count),
+			term.FormatAge(time.Unix(u.last, 0)) + " ago",
+		})
+	}
+	for i, line := range term.FormatTable(rows) {
+		if i == 0 {
+			line = color.New(color.Bold).Sprint(line)
+		}
+		fmt.Println(line)
+	}
+}
+
+func (o *Options) handlePrune() error {
+	shouldPrune := func(record *history.Record) bool {
+		if _, ok := o.configMgr.Get(record.Name); !ok {
+			return true
+		}
+		if !o.olderThanTime.IsZero() && time.Unix(record.Timestamp, 0).Before(o.olderThanTime) {
+			return true
+		}
+		return false
+	}
+
+	// Count in all records in file, the same as the records to delete
+	records, err := o.historyMgr.ListAll()
+	if err != nil {
+		return err
+	}
+	var count int
+	for _, record := range records {
+		if shouldPrune(record) {
+			count++
+		}
+	}
+	if count == 0 {
+		term.PrintHint("No history record to prune")
+		return nil
+	}
+
+	err = term.Confirm(o.skipConfirm, "Do you want to prune %d history record(s)", count)
+	if err != nil {
+		return err
+	}
+
+	o.historyMgr.DeleteFunc(shouldPrune)
+	err = o.historyMgr.Save()
+	if err != nil {
+		return err
+	}
+
+	term.PrintHint("Pruned %d history record(s)", count)
+	return nil
+}
+
+func (o *Options) handleJump(cmdctx *cmd.Context) error {
+	records := o.filterRecords()
+
+	var curName string
+	cur, ok := o.configMgr.Current()
+	if ok {
+		curName = cur.Name
+	}
+	curKey := stateKey(&history.Record{
+		Name:      curName,
+		Namespace: kubeconfig.GetCurrentNamespace(),
+		Context:   kubeconfig.GetCurrentContext(),
+	})
+
+	// One item for each state, the most recent first
+	seen := make(map[string]struct{})
+	var states []*history.Record
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		key := stateKey(record)
+		if _, ok := seen[key]; ok || key == curKey {
+			continue
+		}
+		seen[key] = struct{}{}
+		if _, ok := o.configMgr.Get(record.Name); !ok {
+			continue
+		}
+		states = append(states, record)
+	}
+	if len(states) == 0 {
+		return errors.New("no history record to jump")
+	}
+
+	rows := make([][]string, 0, len(states))
+	for _, state := range states {
+		var ctx string
+		if state.Context != "" {
+			ctx = fmt.Sprintf("@%s", state.Context)
+		}
+		rows = append(rows, []string{
+			state.Name,
+			state.Namespace,
+			ctx,
+			term.FormatAge(time.Unix(state.Timestamp, 0)) + " ago",
+		})
+	}
+	idx, err := fzf.Search(term.FormatTable(rows))
+	if err != nil {
+		return err
+	}
+
+	return o.jump(cmdctx, states[idx])
+}
+
+func (o *Options) jump(cmdctx *cmd.Context, state *history.Record) error {
+	kc, _ := o.configMgr.Get(state.Name)
+
+	hint := fmt.Sprintf("Switch to kubeconfig %q", kc.Name)
+	if state.Namespace != "" {
+		hint += fmt.Sprintf(", namespace %q", state.Namespace)
+	}
+	if state.Context != "" {
+		hint += fmt.Sprintf(", context %q", state.Context)
+	}
+	term.PrintHint("%s", hint)
+
+	err := o.configMgr.Activate(kc)
+	if err != nil {
+		return err
+	}
+
+	err = source.Apply(cmdctx.Config, kc.GenerateSource(state.Namespace, state.Context))
+	if err != nil {
+		return err
+	}
+
+	o.historyMgr.Add(kc.Name, state.Namespace, state.Context)
+	return o.historyMgr.Save()
+}
+
+// filterAllRecords is like filterRecords, but filters all the records in file,
+// not limited by the max records.
+func (o *Options) filterAllRecords() ([]*history.Record, error) {
+	records, err := o.historyMgr.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	return o.filter(records), nil
+}
+
+func (o *Options) filterRecords() []*history.Record {
+	return o.filter(o.historyMgr.List())
+}
+
+func (o *Options) filter(all []*history.Record) []*history.Record {
+	var records []*history.Record
+	for _, record := range all {
+		t := time.Unix(record.Timestamp, 0)
+		if !o.sinceTime.IsZero() && t.Before(o.sinceTime) {
+			continue
+		}
+		if !o.untilTime.IsZero() && t.After(o.untilTime) {
+			continue
+		}
+		if o.name != "" {
+			match, _ := filepath.Match(o.name, record.Name)
+			if !match {
+				continue
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func stateKey(record *history.Record) string {
+	return fmt.Sprintf("%s\x00%s\x00%s", record.Name, record.Namespace, record.Context)
+}
@@ -17,12 +17,17 @@ func New() *cobra.Command {
 		Short: "Print init script, you should source this in the profile",
 		Args:  cobra.ExactArgs(1),
 	}
+
+	c.Flags().StringVarP(&opts.prompt, "prompt", "", "", "print the prompt snippet instead, can be 'starship', 'p10k' or 'ps1'")
+
 	return cmd.Build(c, &opts)
 }
 
 type Options struct {
 	shell   string
 	cmdName string
+
+	prompt string
 }
 
 func (o *Options) Validate(_ *cobra.Command, args []string) error {
@@ -34,6 +39,15 @@ func (o *Options) Validate(_ *cobra.Command, args []string) error {
 }
 
 func (o *Options) Run(cmdctx *cmd.Context) error {
+	if o.prompt != "" {
+		snippet, err := hack.GetPrompt(o.prompt, o.shell)
+		if err != nil {
+			return err
+		}
+		fmt.Print(snippet)
+		return nil
+	}
+
 	name := cmdctx.Config.Command
 	if len(o.cmdName) > 0 {
 		name = o.cmdName
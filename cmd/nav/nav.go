@@ -0,0 +1,164 @@
+package nav
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/history"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/source"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+// envPosition stores the navigation position of session, in format
+// "<top timestamp>:<offset>". The offset is counted from the top of stack. If
+// the top record changed, it means there were new switches after the
+// navigation, so the position is reset to the top.
+const envPosition = "KUBEWRAP_NAV_POSITION"
+
+func NewBack() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "back [N]",
+		Short: "Go back to the previous kubeconfig and namespace visited in this shell",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	opts.direction = 1
+	return cmd.Build(c, &opts)
+}
+
+func NewForward() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "forward [N]",
+		Short: "Go forward to the next kubeconfig and namespace visited in this shell",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	opts.direction = -1
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	// direction is 1 for back, -1 for forward
+	direction int
+
+	steps int
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	o.steps = 1
+	if len(args) > 0 {
+		steps, err := strconv.Atoi(args[0])
+		if err != nil || steps <= 0 {
+			return fmt.Errorf("invalid steps %q, should be a positive number", args[0])
+		}
+		o.steps = steps
+	}
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	session := history.CurrentSession()
+	if session == "" {
+		return errors.New("no shell session found, please source the init script first")
+	}
+
+	cfg := cmdctx.Config
+	configMgr, err := kubeconfig.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+	histMgr, err := history.NewManager(cfg.History.Path, cfg.History.Max)
+	if err != nil {
+		return err
+	}
+
+	records, err := histMgr.ListSession(session)
+	if err != nil {
+		return err
+	}
+	stack := buildStack(records)
+	if len(stack) == 0 {
+		return errors.New("no switch found in this shell")
+	}
+	top := stack[len(stack)-1]
+	offset := currentOffset(top, len(stack))
+
+	target := offset + o.direction*o.steps
+	switch {
+	case target >= len(stack):
+		return fmt.Errorf("cannot go back %d step(s), only %d in this shell", o.steps, len(stack)-1-offset)
+	case target < 0:
+		return fmt.Errorf("cannot go forward %d step(s), only %d in this shell", o.steps, offset)
+	}
+
+	state := stack[len(stack)-1-target]
+	kc, ok := configMgr.Get(state.Name)
+	if !ok {
+		return fmt.Errorf("kubeconfig %q in history not found, you should remove history records", state.Name)
+	}
+
+	hint := fmt.Sprintf("Switch to kubeconfig %q", kc.Name)
+	if state.Namespace != "" {
+		hint += fmt.Sprintf(", namespace %q", state.Namespace)
+	}
+	if state.Context != "" {
+		hint += fmt.Sprintf(", context %q", state.Context)
+	}
+	term.PrintHint("%s", hint)
+
+	err = configMgr.Activate(kc)
+	if err != nil {
+		return err
+	}
+
+	// The navigation doesn't add history records, so that the stack is kept
+	src := kc.GenerateSource(state.Namespace, state.Context)
+	src += fmt.Sprintf("\nexport %s=\"%d:%d\"", envPosition, top.Timestamp, target)
+	return source.Apply(cfg, src)
+}
+
+// buildStack returns the states visited in session, the consecutive duplicate
+// states are merged.
+func buildStack(records []*history.Record) []*history.Record {
+	var stack []*history.Record
+	for _, record := range records {
+		if len(stack) > 0 {
+			last := stack[len(stack)-1]
+			if last.Name == record.Name && last.Namespace == record.Namespace && last.Context == record.Context {
+				continue
+			}
+		}
+		stack = append(stack, record)
+	}
+	return stack
+}
+
+func currentOffset(top *history.Record, stackSize int) int {
+	timestamp, offset, ok := parsePosition(os.Getenv(envPosition))
+	if !ok || timestamp != top.Timestamp || offset >= stackSize {
+		return 0
+	}
+	return offset
+}
+
+func parsePosition(s string) (int64, int, bool) {
+	timestampStr, offsetStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, false
+	}
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return 0, 0, false
+	}
+	return timestamp, offset, true
+}
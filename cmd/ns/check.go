@@ -0,0 +1,126 @@
+package ns
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/fzf"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+)
+
+// maxSuggestions is the max number of close matches to show when the
+// namespace is not found.
+const maxSuggestions = 3
+
+// checkNs validates that the namespace exists. If not, suggests the close
+// matches, and lets the user select one through fzf with the query.
+func (o *Options) checkNs(cmdctx *cmd.Context, configMgr kubeconfig.Manager, curName, ns string) (string, error) {
+	namespaces, err := listNamespacesRaw(cmdctx.Config, cmdctx.Kubectl, curName)
+	if err != nil {
+		if !kubectl.IsForbidden(err) {
+			return "", fmt.Errorf("%w, use `--no-check` to skip the check", err)
+		}
+		return o.checkNsForbidden(cmdctx, configMgr, curName, ns)
+	}
+
+	if slices.Contains(namespaces, ns) {
+		return ns, nil
+	}
+	if len(namespaces) == 0 {
+		return "", fmt.Errorf("cannot find namespace %q", ns)
+	}
+
+	warning := fmt.Sprintf("Namespace %q not found", ns)
+	suggestions := closeMatches(ns, namespaces)
+	if len(suggestions) > 0 {
+		warning += fmt.Sprintf(", did you mean: %s", strings.Join(suggestions, ", "))
+	}
+	fmt.Fprintln(os.Stderr, color.YellowString(warning))
+
+	idx, err := fzf.SearchQuery(namespaces, ns)
+	if err != nil {
+		return "", err
+	}
+	return namespaces[idx], nil
+}
+
+// checkNsForbidden is used when the user has no permission to list
+// namespaces. The discovered namespaces are trusted.
+func (o *Options) checkNsForbidden(cmdctx *cmd.Context, configMgr kubeconfig.Manager, curName, ns string) (string, error) {
+	discovered := discoverNamespaces(cmdctx.Kubectl, configMgr, curName)
+	if slices.Contains(discovered, ns) {
+		return ns, nil
+	}
+
+	err := cmdctx.Kubectl.CheckNamespace(ns)
+	switch {
+	case err == nil:
+		return ns, nil
+
+	case kubectl.IsForbidden(err):
+		// We cannot know whether it exists, trust the user
+		fmt.Fprintln(os.Stderr, color.YellowString("No permission to check namespace %q, use it without check", ns))
+		return ns, nil
+
+	default:
+		return "", err
+	}
+}
+
+// closeMatches returns the candidates whose edit distance to name is small
+// enough, the closest first.
+func closeMatches(name string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := max(len(name)/3, 2)
+	var matches []*match
+	for _, candidate := range candidates {
+		distance := editDistance(name, candidate)
+		if distance > maxDistance && !strings.Contains(candidate, name) {
+			continue
+		}
+		matches = append(matches, &match{name: candidate, distance: distance})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.name)
+		if len(names) >= maxSuggestions {
+			break
+		}
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -1,7 +1,10 @@
 package ns
 
 import (
+	"fmt"
+
 	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/history"
 	"github.com/fioncat/kubewrap/pkg/kubeconfig"
 	"github.com/fioncat/kubewrap/pkg/kubectl"
 	"github.com/spf13/cobra"
@@ -10,7 +13,7 @@ import (
 func CompletionFunc(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	cfg := cmd.GetCompleteConfig(c)
 
-	mgr, err := kubeconfig.NewManager(cfg.KubeConfig.Root, cfg.KubeConfig.Alias)
+	mgr, err := kubeconfig.NewManager(cfg)
 	if err != nil {
 		cmd.WriteCompleteLogs("init kubeconfig manager failed: %v", err)
 		return nil, cobra.ShellCompDirectiveError
@@ -21,13 +24,25 @@ func CompletionFunc(c *cobra.Command, args []string, toComplete string) ([]strin
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	kubectl := kubectl.NewCommand(cfg.Kubectl.Name, cfg.Kubectl.Args)
+	kubectl := kubectl.NewCommand(cfg.Kubectl.Name, cmd.KubectlArgs(cfg))
 
-	items, err := listNamespaces(cfg, kubectl, cur.Name)
+	histMgr, err := history.NewManager(cfg.History.Path, cfg.History.Max)
+	if err != nil {
+		cmd.WriteCompleteLogs("init history manager failed: %v", err)
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	items, err := listNamespaces(cfg, kubectl, mgr, histMgr, cur.Name)
 	if err != nil {
 		cmd.WriteCompleteLogs("list namespaces: %v", err)
 		return nil, cobra.ShellCompDirectiveError
 	}
 
+	for _, nsAlias := range cfg.MatchNamespaceAliases(cur.Name) {
+		for short, long := range nsAlias.Short {
+			items = append(items, fmt.Sprintf("%s\t%s", short, long))
+		}
+	}
+
 	return items, cobra.ShellCompDirectiveNoFileComp
 }
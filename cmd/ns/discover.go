@@ -0,0 +1,89 @@
+package ns
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/history"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+)
+
+// listNamespacesFallback is like listNamespacesRaw, but when the user has no
+// permission to list namespaces, discovers them in other ways, see
+// discoverNamespaces. The namespaces used before are listed as well, they may
+// not exist anymore, but are useful candidates to select.
+func listNamespacesFallback(cfg *config.Config, k kubectl.Kubectl, configMgr kubeconfig.Manager, histMgr history.Manager, curName string) ([]string, error) {
+	namespaces, err := listNamespacesRaw(cfg, k, curName)
+	if err == nil || !kubectl.IsForbidden(err) {
+		return namespaces, err
+	}
+
+	namespaces = discoverNamespaces(k, configMgr, curName)
+	if histMgr != nil {
+		records := histMgr.List()
+		for i := len(records) - 1; i >= 0; i-- {
+			ns := records[i].Namespace
+			if records[i].Name == curName && ns != "" && !slices.Contains(namespaces, ns) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("%w, and no namespace discovered, use `kw ns NAME --no-check` to switch directly", err)
+	}
+	return namespaces, nil
+}
+
+// discoverNamespaces finds the namespaces that the user may access without the
+// permission to list namespaces, in order:
+//
+//   - The namespace of the context in kubeconfig.
+//   - The namespaces allowed by name in the access rules (`kubectl auth can-i --list`).
+//
+// The errors are ignored, since every way is optional.
+func discoverNamespaces(k kubectl.Kubectl, configMgr kubeconfig.Manager, curName string) []string {
+	var namespaces []string
+	add := func(ns string) {
+		if ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	contextNs, _ := getContextNamespace(configMgr, curName)
+	add(contextNs)
+
+	rulesNs := contextNs
+	if rulesNs == "" {
+		rulesNs = "default"
+	}
+	permitted, _ := k.ListPermittedNamespaces(rulesNs)
+	for _, ns := range permitted {
+		add(ns)
+	}
+
+	return namespaces
+}
+
+func getContextNamespace(configMgr kubeconfig.Manager, name string) (string, error) {
+	data, err := configMgr.Read(name)
+	if err != nil {
+		return "", err
+	}
+	file, err := kubeconfig.ParseFile(data)
+	if err != nil {
+		return "", err
+	}
+
+	contextName := kubeconfig.GetCurrentContext()
+	if contextName == "" {
+		contextName = file.CurrentContext
+	}
+	ctx := file.GetContext(contextName)
+	if ctx == nil {
+		return "", errors.New("context not found")
+	}
+	return ctx.Namespace, nil
+}
@@ -3,7 +3,6 @@ package ns
 import (
 	"errors"
 	"fmt"
-	"path/filepath"
 
 	"github.com/fatih/color"
 	"github.com/fioncat/kubewrap/cmd"
@@ -30,6 +29,7 @@ func New() *cobra.Command {
 	c.Flags().BoolVarP(&opts.unuse, "unuse", "u", false, "unuse namespace")
 	c.Flags().BoolVarP(&opts.list, "list", "l", false, "list namespaces")
 	c.Flags().BoolVarP(&opts.listHistory, "list-history", "H", false, "show namespace history")
+	c.Flags().BoolVarP(&opts.noCheck, "no-check", "", false, "don't check whether the namespace exists, useful when offline")
 
 	return cmd.Build(c, &opts)
 }
@@ -41,6 +41,8 @@ type Options struct {
 
 	list        bool
 	listHistory bool
+
+	noCheck bool
 }
 
 func (o *Options) Validate(_ *cobra.Command, args []string) error {
@@ -52,7 +54,7 @@ func (o *Options) Validate(_ *cobra.Command, args []string) error {
 
 func (o *Options) Run(cmdctx *cmd.Context) error {
 	cfg := cmdctx.Config
-	configMgr, err := kubeconfig.NewManager(cfg.KubeConfig.Root, cfg.KubeConfig.Alias)
+	configMgr, err := kubeconfig.NewManager(cfg)
 	if err != nil {
 		return err
 	}
@@ -70,7 +72,7 @@ func (o *Options) Run(cmdctx *cmd.Context) error {
 	if o.list {
 		curNs := kubeconfig.GetCurrentNamespace()
 		var nsList []string
-		nsList, err = listNamespacesRaw(cmdctx.Config, cmdctx.Kubectl, cur.Name)
+		nsList, err = listNamespacesFallback(cmdctx.Config, cmdctx.Kubectl, configMgr, histMgr, cur.Name)
 		if err != nil {
 			return err
 		}
@@ -103,25 +105,25 @@ func (o *Options) Run(cmdctx *cmd.Context) error {
 			return errors.New("no current namespace used, cannot unuse")
 		}
 		term.PrintHint("Unuse current namespace %q", curNs)
-		return source.Apply(cfg, cur.GenerateSource(""))
+		return source.Apply(cfg, cur.GenerateSource("", kubeconfig.GetCurrentContext()))
 	}
 
-	ns, err := o.selectNs(cmdctx, cur.Name, histMgr)
+	ns, err := o.selectNs(cmdctx, configMgr, cur.Name, histMgr)
 	if err != nil {
 		return err
 	}
 
 	term.PrintHint("Switch to namespace %q", ns)
-	err = source.Apply(cfg, cur.GenerateSource(ns))
+	err = source.Apply(cfg, cur.GenerateSource(ns, kubeconfig.GetCurrentContext()))
 	if err != nil {
 		return err
 	}
 
-	histMgr.Add(cur.Name, ns)
+	histMgr.Add(cur.Name, ns, kubeconfig.GetCurrentContext())
 	return histMgr.Save()
 }
 
-func (o *Options) selectNs(cmdctx *cmd.Context, curName string, histMgr history.Manager) (string, error) {
+func (o *Options) selectNs(cmdctx *cmd.Context, configMgr kubeconfig.Manager, curName string, histMgr history.Manager) (string, error) {
 	if o.namespace == "-" {
 		curNamespace := kubeconfig.GetCurrentNamespace()
 		lastNsPtr := histMgr.GetLastNamespace(curName, curNamespace)
@@ -132,13 +134,20 @@ func (o *Options) selectNs(cmdctx *cmd.Context, curName string, histMgr history.
 	}
 
 	if o.namespace != "" {
-		return o.namespace, nil
+		ns := cmdctx.Config.ExpandNamespace(curName, o.namespace)
+		if o.noCheck {
+			return ns, nil
+		}
+		return o.checkNs(cmdctx, configMgr, curName, ns)
 	}
 
-	items, err := listNamespaces(cmdctx.Config, cmdctx.Kubectl, curName)
+	items, err := listNamespaces(cmdctx.Config, cmdctx.Kubectl, configMgr, histMgr, curName)
 	if err != nil {
 		return "", err
 	}
+	scores := history.NamespaceScores(histMgr.List(), curName)
+	history.Sort(items, func(ns string) string { return ns }, scores)
+
 	idx, err := fzf.Search(items)
 	if err != nil {
 		return "", err
@@ -147,8 +156,8 @@ func (o *Options) selectNs(cmdctx *cmd.Context, curName string, histMgr history.
 	return items[idx], nil
 }
 
-func listNamespaces(cfg *config.Config, kubectl kubectl.Kubectl, curName string) ([]string, error) {
-	nsList, err := listNamespacesRaw(cfg, kubectl, curName)
+func listNamespaces(cfg *config.Config, kubectl kubectl.Kubectl, configMgr kubeconfig.Manager, histMgr history.Manager, curName string) ([]string, error) {
+	nsList, err := listNamespacesFallback(cfg, kubectl, configMgr, histMgr, curName)
 	if err != nil {
 		return nil, err
 	}
@@ -165,20 +174,9 @@ func listNamespaces(cfg *config.Config, kubectl kubectl.Kubectl, curName string)
 }
 
 func listNamespacesRaw(cfg *config.Config, kubectl kubectl.Kubectl, curName string) ([]string, error) {
-	for _, nsAlias := range cfg.NamespaceAlias {
-		for _, pattern := range nsAlias.Pattern {
-			match, err := filepath.Match(pattern, curName)
-			if err != nil {
-				return nil, fmt.Errorf("invalid namespace alias pattern %q: %w", pattern, err)
-			}
-			if match {
-				return nsAlias.Namespaces, nil
-			}
-		}
-		for _, name := range nsAlias.Configs {
-			if name == curName {
-				return nsAlias.Namespaces, nil
-			}
+	for _, nsAlias := range cfg.MatchNamespaceAliases(curName) {
+		if len(nsAlias.Namespaces) > 0 {
+			return nsAlias.Namespaces, nil
 		}
 	}
 
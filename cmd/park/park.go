@@ -0,0 +1,105 @@
+package park
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+// The workload types to park, their replicas will be scaled to 0
+var parkResourceTypes = []string{"deploy", "sts"}
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "park [NAMESPACE]",
+		Short: "Scale all workloads in a namespace to 0, use unpark to restore",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	c.Flags().BoolVarP(&opts.dryRun, "dry-run", "", false, "only show the preview, don't scale")
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	namespace string
+
+	dryRun      bool
+	skipConfirm bool
+}
+
+type workload struct {
+	resource *kubectl.Resource
+	replicas int
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		o.namespace = args[0]
+	}
+	if o.namespace == "" {
+		o.namespace = cmd.GetCurrentNamespace()
+	}
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	k := cmdctx.Kubectl
+	all, err := k.ListWorkloads(parkResourceTypes, o.namespace)
+	if err != nil {
+		return err
+	}
+
+	var workloads []*workload
+	for _, w := range all {
+		if w.Replicas == 0 {
+			continue
+		}
+		workloads = append(workloads, &workload{resource: &w.Resource, replicas: w.Replicas})
+	}
+
+	if len(workloads) == 0 {
+		term.PrintHint("No running workload to park in namespace %q", o.namespace)
+		return nil
+	}
+
+	rows := make([][]string, 0, len(workloads))
+	for _, w := range workloads {
+		rows = append(rows, []string{w.resource.String(), strconv.Itoa(w.replicas), "->", "0"})
+	}
+	printPreview(rows)
+	if o.dryRun {
+		return nil
+	}
+
+	err = term.Confirm(o.skipConfirm, "Park %d workload(s) in namespace %q", len(workloads), o.namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range workloads {
+		err = k.Annotate(w.resource, kubectl.AnnotationParkedReplicas, strconv.Itoa(w.replicas))
+		if err != nil {
+			return err
+		}
+		err = k.Scale(w.resource, 0)
+		if err != nil {
+			return err
+		}
+		term.PrintHint("Parked %v", w.resource)
+	}
+
+	return nil
+}
+
+func printPreview(rows [][]string) {
+	for _, line := range term.FormatTable(rows) {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,96 @@
+package park
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func NewUnpark() *cobra.Command {
+	var opts UnparkOptions
+	c := &cobra.Command{
+		Use:   "unpark [NAMESPACE]",
+		Short: "Restore the workloads parked by park command",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	c.Flags().BoolVarP(&opts.dryRun, "dry-run", "", false, "only show the preview, don't scale")
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
+	return cmd.Build(c, &opts)
+}
+
+type UnparkOptions struct {
+	namespace string
+
+	dryRun      bool
+	skipConfirm bool
+}
+
+func (o *UnparkOptions) Validate(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		o.namespace = args[0]
+	}
+	if o.namespace == "" {
+		o.namespace = cmd.GetCurrentNamespace()
+	}
+	return nil
+}
+
+func (o *UnparkOptions) Run(cmdctx *cmd.Context) error {
+	k := cmdctx.Kubectl
+	all, err := k.ListWorkloads(parkResourceTypes, o.namespace)
+	if err != nil {
+		return err
+	}
+
+	var workloads []*workload
+	for _, w := range all {
+		value := w.Annotations[kubectl.AnnotationParkedReplicas]
+		if value == "" {
+			continue
+		}
+		replicas, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid parked replicas %q for %v", value, &w.Resource)
+		}
+		workloads = append(workloads, &workload{resource: &w.Resource, replicas: replicas})
+	}
+
+	if len(workloads) == 0 {
+		term.PrintHint("No parked workload in namespace %q", o.namespace)
+		return nil
+	}
+
+	rows := make([][]string, 0, len(workloads))
+	for _, w := range workloads {
+		rows = append(rows, []string{w.resource.String(), "0", "->", strconv.Itoa(w.replicas)})
+	}
+	printPreview(rows)
+	if o.dryRun {
+		return nil
+	}
+
+	err = term.Confirm(o.skipConfirm, "Unpark %d workload(s) in namespace %q", len(workloads), o.namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range workloads {
+		err = k.Scale(w.resource, w.replicas)
+		if err != nil {
+			return err
+		}
+		err = k.RemoveAnnotation(w.resource, kubectl.AnnotationParkedReplicas)
+		if err != nil {
+			return err
+		}
+		term.PrintHint("Unparked %v to %d replicas", w.resource, w.replicas)
+	}
+
+	return nil
+}
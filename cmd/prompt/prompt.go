@@ -0,0 +1,132 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+var colorCodes = map[string]int{
+	"black":   30,
+	"red":     31,
+	"green":   32,
+	"yellow":  33,
+	"blue":    34,
+	"magenta": 35,
+	"cyan":    36,
+	"white":   37,
+}
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "prompt",
+		Short: "Print the current kubeconfig and namespace for shell prompt",
+		Long: `Print the current kubeconfig and namespace for shell prompt.
+
+This only reads env and local state files (never calls kubectl), so that it is
+fast enough to be called for every prompt. Print nothing if no kubeconfig is
+selected.
+
+The format supports placeholders "{config}", "{ns}", "{context}" and "{env}",
+the env is read from the tag "prompt.env_tag" of kubeconfig, and used to select
+the color from "prompt.env_colors".
+
+Use "--shell" to wrap the color codes so that the shell can compute the prompt
+width correctly. See "init --prompt" for the ready-made snippets.`,
+		Args: cobra.NoArgs,
+	}
+
+	c.Flags().StringVarP(&opts.format, "format", "f", "", "the format of prompt, default is `prompt.format` in config")
+	c.Flags().BoolVarP(&opts.noColor, "no-color", "", false, "don't print color")
+	c.Flags().StringVarP(&opts.shell, "shell", "", "", "the shell to wrap color codes for, can be 'bash' or 'zsh', empty means raw ANSI codes")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	format  string
+	noColor bool
+	shell   string
+}
+
+func (o *Options) Validate(_ *cobra.Command, _ []string) error {
+	switch o.shell {
+	case "", "bash", "zsh":
+		return nil
+	default:
+		return fmt.Errorf("unknown shell type: %s", o.shell)
+	}
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	name := kubeconfig.GetCurrentName()
+	if name == "" {
+		return nil
+	}
+	cfg := cmdctx.Config.Prompt
+
+	format := o.format
+	if format == "" {
+		format = cfg.Format
+	}
+
+	var env string
+	if strings.Contains(format, "{env}") || !o.noColor {
+		meta, err := kubeconfig.LoadMetadata(cmdctx.Config, name)
+		if err != nil {
+			return err
+		}
+		env = meta.Tags[cfg.EnvTag]
+	}
+
+	ns := kubeconfig.GetCurrentNamespace()
+	context := kubeconfig.GetCurrentContext()
+	if o.shell == "zsh" {
+		// The "%" is special in zsh prompt
+		name, ns, context, env = zshEscape(name), zshEscape(ns), zshEscape(context), zshEscape(env)
+	}
+	text := strings.NewReplacer(
+		"{config}", name,
+		"{ns}", ns,
+		"{context}", context,
+		"{env}", env,
+	).Replace(format)
+
+	color := cfg.Color
+	if envColor, ok := cfg.EnvColors[env]; ok && env != "" {
+		color = envColor
+	}
+	if !o.noColor {
+		text = o.colorize(text, color)
+	}
+
+	fmt.Println(text)
+	return nil
+}
+
+func (o *Options) colorize(text, color string) string {
+	code, ok := colorCodes[color]
+	if !ok {
+		// The "none" color
+		return text
+	}
+
+	switch o.shell {
+	case "bash":
+		// "\001" and "\002" mark the non-printing characters for readline, they
+		// work in the output of command substitution, unlike "\[" and "\]"
+		return fmt.Sprintf("\001\033[%dm\002%s\001\033[0m\002", code, text)
+	case "zsh":
+		return fmt.Sprintf("%%F{%s}%s%%f", color, text)
+	default:
+		return fmt.Sprintf("\033[%dm%s\033[0m", code, text)
+	}
+}
+
+func zshEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
@@ -0,0 +1,204 @@
+package rollout
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/diff"
+	"github.com/fioncat/kubewrap/pkg/fzf"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+	"github.com/fioncat/kubewrap/pkg/term"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+func New() *cobra.Command {
+	var opts Options
+	c := &cobra.Command{
+		Use:   "rollout <QUERY>",
+		Short: "Show rollout history of a resource, diff and undo to a revision",
+		Args:  cobra.ExactArgs(1),
+
+		ValidArgsFunction: CompletionFunc,
+	}
+
+	c.Flags().BoolVarP(&opts.history, "history", "H", false, "only show the rollout history")
+	c.Flags().BoolVarP(&opts.diff, "diff", "d", false, "only show the diff between current and selected revision, don't undo")
+	c.Flags().Int64VarP(&opts.revision, "to-revision", "r", 0, "the revision to use, default will select with fzf")
+
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
+	return cmd.Build(c, &opts)
+}
+
+type Options struct {
+	query string
+
+	history bool
+	diff    bool
+
+	revision int64
+
+	skipConfirm bool
+}
+
+func (o *Options) Validate(_ *cobra.Command, args []string) error {
+	o.query = args[0]
+
+	if o.history && o.diff {
+		return errors.New("`--history` and `--diff` cannot be used together")
+	}
+	if o.revision < 0 {
+		return errors.New("revision must be greater than 0")
+	}
+
+	return nil
+}
+
+func (o *Options) Run(cmdctx *cmd.Context) error {
+	r, err := cmd.SelectResource(cmdctx.Kubectl, o.query)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := cmdctx.Kubectl.ListRevisions(r)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return fmt.Errorf("no revision found for %v", r)
+	}
+	current := revisions[len(revisions)-1]
+
+	if o.history {
+		o.printHistory(revisions, current)
+		return nil
+	}
+
+	target, err := o.selectRevision(revisions, current)
+	if err != nil {
+		return err
+	}
+
+	err = o.showDiff(cmdctx.Kubectl, r, current, target)
+	if err != nil {
+		return err
+	}
+	if o.diff {
+		return nil
+	}
+
+	err = term.Confirm(o.skipConfirm, "Rollback %v to revision %d", r, target.Number)
+	if err != nil {
+		return err
+	}
+
+	err = cmdctx.Kubectl.RolloutUndo(r, target.Number)
+	if err != nil {
+		return err
+	}
+
+	term.PrintHint("Rolled back %v to revision %d", r, target.Number)
+	return nil
+}
+
+func (o *Options) printHistory(revisions []*kubectl.Revision, current *kubectl.Revision) {
+	rows := make([][]string, 0, len(revisions)+1)
+	rows = append(rows, []string{"REVISION", "AGE", "IMAGES", "CHANGE-CAUSE"})
+	rows = append(rows, formatRevisions(revisions)...)
+
+	lines := term.FormatTable(rows)
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			line = color.New(color.Bold).Sprint(line)
+		case revisions[i-1] == current:
+			line = color.New(color.Bold, color.FgGreen).Sprint(line)
+		}
+		fmt.Println(line)
+	}
+}
+
+func (o *Options) selectRevision(revisions []*kubectl.Revision, current *kubectl.Revision) (*kubectl.Revision, error) {
+	if o.revision > 0 {
+		for _, revision := range revisions {
+			if revision.Number == o.revision {
+				return revision, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot find revision %d", o.revision)
+	}
+
+	candidates := make([]*kubectl.Revision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i] == current {
+			continue
+		}
+		candidates = append(candidates, revisions[i])
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("no other revision to select")
+	}
+
+	items := term.FormatTable(formatRevisions(candidates))
+	idx, err := fzf.Search(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return candidates[idx], nil
+}
+
+func (o *Options) showDiff(k kubectl.Kubectl, r *kubectl.Resource, current, target *kubectl.Revision) error {
+	data, err := k.GetResource(r)
+	if err != nil {
+		return err
+	}
+	currentTemplate, err := kubectl.PodTemplate(data)
+	if err != nil {
+		return err
+	}
+
+	currentYaml, err := yaml.JSONToYAML(currentTemplate)
+	if err != nil {
+		return fmt.Errorf("convert current template to yaml: %w", err)
+	}
+	targetYaml, err := yaml.JSONToYAML(target.Template)
+	if err != nil {
+		return fmt.Errorf("convert revision template to yaml: %w", err)
+	}
+
+	result, err := diff.Diff(fmt.Sprintf("revision %d (current)", current.Number), currentYaml,
+		fmt.Sprintf("revision %d", target.Number), targetYaml)
+	if err != nil {
+		return err
+	}
+
+	if result == "" {
+		term.PrintHint("No difference between revision %d and current", target.Number)
+		return nil
+	}
+
+	diff.Print(result)
+	return nil
+}
+
+func formatRevisions(revisions []*kubectl.Revision) [][]string {
+	rows := make([][]string, 0, len(revisions))
+	for _, revision := range revisions {
+		changeCause := revision.ChangeCause
+		if changeCause == "" {
+			changeCause = "<none>"
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(revision.Number),
+			term.FormatAge(revision.CreateTime),
+			strings.Join(revision.Images, ","),
+			changeCause,
+		})
+	}
+	return rows
+}
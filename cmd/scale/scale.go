@@ -3,9 +3,13 @@ package scale
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
 	"github.com/fioncat/kubewrap/pkg/term"
 	"github.com/spf13/cobra"
 )
@@ -13,29 +17,71 @@ import (
 func New() *cobra.Command {
 	var opts Options
 	c := &cobra.Command{
-		Use:   "scale <QUERY> <REPLICAS>",
+		Use:   "scale <QUERY> [REPLICAS]",
 		Short: "Scale the replicas of a resource",
-		Args:  cobra.ExactArgs(2),
+		Long: `Scale the replicas of a resource.
+
+The REPLICAS can be an absolute number ("3"), relative to current replicas
+("+2", "-1") or a multiple of current replicas ("x2"). The replicas before
+scaling are saved in the resource annotation, use "--restore" to scale back.
+
+The replicas starting with "-" would be parsed as flags, put them after "--",
+for example "scale deploy/api -- -1".`,
+		Args: cobra.RangeArgs(1, 2),
 
 		ValidArgsFunction: CompletionFunc,
 	}
+
+	c.Flags().BoolVarP(&opts.restore, "restore", "r", false, "restore the replicas before last scaling")
+
+	c.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		// Such as "unknown shorthand flag: '1' in -1"
+		if strings.HasPrefix(err.Error(), "unknown shorthand flag") && strings.ContainsAny(err.Error(), "0123456789") {
+			return fmt.Errorf("%w, use `--` before the negative replicas, such as `-- -1`", err)
+		}
+		return err
+	})
+
 	return cmd.Build(c, &opts)
 }
 
 type Options struct {
-	query    string
-	replicas int
+	query string
+
+	op    byte
+	value int
+
+	restore bool
 }
 
 func (o *Options) Validate(_ *cobra.Command, args []string) error {
 	o.query = args[0]
 
+	if o.restore {
+		if len(args) > 1 {
+			return errors.New("replicas cannot be specified with `--restore`")
+		}
+		return nil
+	}
+	if len(args) < 2 {
+		return errors.New("replicas is required")
+	}
+
+	expr := args[1]
+	if len(expr) > 0 {
+		switch expr[0] {
+		case '+', '-', 'x':
+			o.op = expr[0]
+			expr = expr[1:]
+		}
+	}
+
 	var err error
-	o.replicas, err = strconv.Atoi(args[1])
+	o.value, err = strconv.Atoi(expr)
 	if err != nil {
-		return fmt.Errorf("replicas must be an integer: %w", err)
+		return fmt.Errorf("replicas must be an integer, optionally prefixed with '+', '-' or 'x': %w", err)
 	}
-	if o.replicas < 0 {
+	if o.value < 0 {
 		return errors.New("replicas must be greater than or equal to 0")
 	}
 
@@ -47,11 +93,96 @@ func (o *Options) Run(cmdctx *cmd.Context) error {
 	if err != nil {
 		return err
 	}
-	err = cmdctx.Kubectl.Scale(r, o.replicas)
+
+	current, err := cmdctx.Kubectl.GetReplicas(r)
+	if err != nil {
+		return err
+	}
+
+	replicas, err := o.getReplicas(cmdctx.Kubectl, r, current)
+	if err != nil {
+		return err
+	}
+
+	err = checkBounds(cmdctx.Config, replicas)
+	if err != nil {
+		return err
+	}
+
+	if replicas == current {
+		term.PrintHint("%v already has %d replicas", r, current)
+		if o.restore {
+			return cmdctx.Kubectl.RemoveAnnotation(r, kubectl.AnnotationPreviousReplicas)
+		}
+		return nil
+	}
+
+	hpa, err := cmdctx.Kubectl.FindHPA(r)
+	switch {
+	case err != nil:
+		// The HPA check is only a hint, such as no permission to get HPA
+		fmt.Fprintf(os.Stderr, "WARNING: cannot check HPA of %v: %v\n", r, err)
+	case hpa != "":
+		fmt.Fprintf(os.Stderr, "WARNING: %v is managed by HPA %q, the replicas may be changed back by it\n", r, hpa)
+	}
+
+	err = cmdctx.Kubectl.Scale(r, replicas)
 	if err != nil {
 		return err
 	}
 
-	term.PrintHint("Scaled %v to %d", r, o.replicas)
+	// Update the annotation after scaling succeeded, so that the saved
+	// replicas are kept if scaling failed
+	if o.restore {
+		err = cmdctx.Kubectl.RemoveAnnotation(r, kubectl.AnnotationPreviousReplicas)
+	} else {
+		err = cmdctx.Kubectl.Annotate(r, kubectl.AnnotationPreviousReplicas, strconv.Itoa(current))
+	}
+	if err != nil {
+		return fmt.Errorf("scaled %v, but failed to update annotation: %w", r, err)
+	}
+
+	term.PrintHint("Scaled %v from %d to %d", r, current, replicas)
+	return nil
+}
+
+func (o *Options) getReplicas(k kubectl.Kubectl, r *kubectl.Resource, current int) (int, error) {
+	if o.restore {
+		value, err := k.GetAnnotation(r, kubectl.AnnotationPreviousReplicas)
+		if err != nil {
+			return 0, err
+		}
+		if value == "" {
+			return 0, fmt.Errorf("no previous replicas saved for %v", r)
+		}
+		replicas, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid previous replicas %q for %v", value, r)
+		}
+		return replicas, nil
+	}
+
+	switch o.op {
+	case '+':
+		return current + o.value, nil
+	case '-':
+		if o.value > current {
+			return 0, fmt.Errorf("cannot scale down %v by %d, it only has %d replicas", r, o.value, current)
+		}
+		return current - o.value, nil
+	case 'x':
+		return current * o.value, nil
+	default:
+		return o.value, nil
+	}
+}
+
+func checkBounds(cfg *config.Config, replicas int) error {
+	if replicas < cfg.Scale.Min {
+		return fmt.Errorf("replicas %d is less than the min bound %d in config", replicas, cfg.Scale.Min)
+	}
+	if cfg.Scale.Max > 0 && replicas > cfg.Scale.Max {
+		return fmt.Errorf("replicas %d is greater than the max bound %d in config", replicas, cfg.Scale.Max)
+	}
 	return nil
 }
@@ -20,7 +20,7 @@ func SelectResource(k kubectl.Kubectl, query string) (*kubectl.Resource, error)
 		return nil, fmt.Errorf("invalid resource query %q, type is required", query)
 	}
 
-	namespace := getCurrentNamespace()
+	namespace := GetCurrentNamespace()
 
 	var name string
 	if len(fields) == 2 {
@@ -71,7 +71,7 @@ func SelectContainer(k kubectl.Kubectl, query string) (*kubectl.Container, error
 		name = fields[1]
 	}
 
-	namespace := getCurrentNamespace()
+	namespace := GetCurrentNamespace()
 
 	if name == "" {
 		return selectContainerByResourceType(k, resourceType, namespace)
@@ -161,7 +161,7 @@ func selectContainerByResourceType(k kubectl.Kubectl, resourceType, namespace st
 	return citems[idx].container, nil
 }
 
-func getCurrentNamespace() string {
+func GetCurrentNamespace() string {
 	namespace := kubeconfig.GetCurrentNamespace()
 	if namespace == "" {
 		return "default"
@@ -2,8 +2,13 @@ package setimage
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/fioncat/kubewrap/cmd"
+	"github.com/fioncat/kubewrap/pkg/image"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
 	"github.com/fioncat/kubewrap/pkg/term"
 	"github.com/spf13/cobra"
 )
@@ -13,16 +18,37 @@ func New() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "set-image <QUERY> <IMAGE>",
 		Short: "Set the image of a container",
-		Args:  cobra.ExactArgs(2),
+		Long: `Set the image of a container.
+
+The IMAGE can be a full image reference, or only a tag (":v1.2.3") or digest
+("@sha256:...") to keep the current repository and swap only the tag or digest.
+
+With "--all-containers-matching", the QUERY selects workloads ("<type>" for all
+workloads of the type, or "<type>/<name>"), and every container using the repo
+is updated.`,
+		Args: cobra.ExactArgs(2),
 
 		ValidArgsFunction: CompletionFunc,
 	}
+
+	c.Flags().StringVarP(&opts.matchRepo, "all-containers-matching", "m", "", "update all containers using this repo in the selected workloads")
+	c.Flags().BoolVarP(&opts.skipConfirm, "noconfirm", "y", false, "skip confirm")
+
 	return cmd.Build(c, &opts)
 }
 
 type Options struct {
 	query string
 	image string
+
+	matchRepo string
+
+	skipConfirm bool
+}
+
+type update struct {
+	container *kubectl.Container
+	image     string
 }
 
 func (o *Options) Validate(_ *cobra.Command, args []string) error {
@@ -37,15 +63,133 @@ func (o *Options) Validate(_ *cobra.Command, args []string) error {
 }
 
 func (o *Options) Run(cmdctx *cmd.Context) error {
-	c, err := cmd.SelectContainer(cmdctx.Kubectl, o.query)
+	var updates []*update
+	var err error
+	if o.matchRepo != "" {
+		updates, err = o.selectMatching(cmdctx.Kubectl)
+	} else {
+		updates, err = o.selectOne(cmdctx.Kubectl)
+	}
 	if err != nil {
 		return err
 	}
-	err = cmdctx.Kubectl.SetImage(c, o.image)
+
+	if len(updates) == 0 {
+		term.PrintHint("No image to update")
+		return nil
+	}
+
+	o.preview(updates)
+	err = term.Confirm(o.skipConfirm, "Update %d container(s)", len(updates))
 	if err != nil {
 		return err
 	}
 
-	term.PrintHint("Set image of %v to %q", c, o.image)
+	for _, u := range updates {
+		err = cmdctx.Kubectl.SetImage(u.container, u.image)
+		if err != nil {
+			return err
+		}
+		term.PrintHint("Set image of %v to %q", u.container, u.image)
+	}
+
 	return nil
 }
+
+func (o *Options) selectOne(k kubectl.Kubectl) ([]*update, error) {
+	c, err := cmd.SelectContainer(k, o.query)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Image == "" {
+		// The container name was given in query, we need to get the image
+		// from cluster.
+		var cs []*kubectl.Container
+		cs, err = k.ListContainers(&c.Resource)
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range cs {
+			if container.ContainerName == c.ContainerName {
+				c = container
+				break
+			}
+		}
+		if c.Image == "" && image.IsPartial(o.image) {
+			return nil, fmt.Errorf("cannot find image of %v", c)
+		}
+	}
+
+	newImage, err := image.Update(c.Image, o.image)
+	if err != nil {
+		return nil, err
+	}
+	if newImage == c.Image {
+		return nil, nil
+	}
+
+	return []*update{{container: c, image: newImage}}, nil
+}
+
+func (o *Options) selectMatching(k kubectl.Kubectl) ([]*update, error) {
+	fields := strings.Split(o.query, "/")
+	if len(fields) != 1 && len(fields) != 2 {
+		return nil, fmt.Errorf("invalid resource query %q, should be '<type>[/name]'", o.query)
+	}
+
+	var rs []*kubectl.Resource
+	if len(fields) == 2 && fields[1] != "" {
+		r, err := cmd.SelectResource(k, o.query)
+		if err != nil {
+			return nil, err
+		}
+		rs = []*kubectl.Resource{r}
+	} else {
+		var err error
+		rs, err = k.ListResources(fields[0], cmd.GetCurrentNamespace())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var updates []*update
+	for _, r := range rs {
+		cs, err := k.ListContainers(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cs {
+			if !image.MatchRepository(c.Image, o.matchRepo) {
+				continue
+			}
+			newImage, err := image.Update(c.Image, o.image)
+			if err != nil {
+				return nil, err
+			}
+			if newImage == c.Image {
+				continue
+			}
+			updates = append(updates, &update{container: c, image: newImage})
+		}
+	}
+
+	return updates, nil
+}
+
+func (o *Options) preview(updates []*update) {
+	rows := make([][]string, 0, len(updates))
+	for _, u := range updates {
+		rows = append(rows, []string{u.container.String(), u.container.Image, "->", u.image})
+	}
+	for i, line := range term.FormatTable(rows) {
+		// The image columns are aligned, color them after formatting
+		u := updates[i]
+		name := u.container.String()
+		rest := strings.TrimSuffix(line[len(name):], u.image)
+		if u.container.Image != "" {
+			rest = strings.Replace(rest, u.container.Image, color.RedString(u.container.Image), 1)
+		}
+		fmt.Println(name + rest + color.GreenString(u.image))
+	}
+}
@@ -13,7 +13,7 @@ func New() *cobra.Command {
 	var opts Options
 	c := &cobra.Command{
 		Use:   "show",
-		Short: "Print current selected kubeconfig and namespace",
+		Short: "Print current selected kubeconfig, context and namespace",
 		Args:  cobra.NoArgs,
 	}
 	return cmd.Build(c, &opts)
@@ -25,7 +25,7 @@ func (o *Options) Validate(_ *cobra.Command, _ []string) error { return nil }
 
 func (o *Options) Run(cmdctx *cmd.Context) error {
 	cfg := cmdctx.Config
-	mgr, err := kubeconfig.NewManager(cfg.KubeConfig.Root, cfg.KubeConfig.Alias)
+	mgr, err := kubeconfig.NewManager(cfg)
 	if err != nil {
 		return err
 	}
@@ -38,6 +38,10 @@ func (o *Options) Run(cmdctx *cmd.Context) error {
 	ns := kubeconfig.GetCurrentNamespace()
 
 	str := cur.String()
+	context := kubeconfig.GetCurrentContext()
+	if context != "" {
+		str = fmt.Sprintf("%s (context %s)", str, context)
+	}
 	if ns != "" {
 		str = fmt.Sprintf("%s -> %s", str, ns)
 	}
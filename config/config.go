@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -20,12 +22,23 @@ type Config struct {
 
 	Editor string `json:"editor" toml:"editor"`
 
+	// EditorExt is the extension of the files to edit, the editor can use it
+	// to enable syntax highlighting.
+	EditorExt string `json:"editor_ext" toml:"editor_ext"`
+
 	SourceFilePath string `json:"source_file_path" toml:"source_file_path"`
 
+	// RuntimeDir stores the short-lived states, such as decrypted kubeconfig
+	// files and caches, default will use "$XDG_RUNTIME_DIR/kubewrap" or a
+	// directory in tmp
+	RuntimeDir string `json:"runtime_dir" toml:"runtime_dir"`
+
 	Kubectl    Kubectl    `json:"kubectl" toml:"kubectl"`
 	NodeShell  NodeShell  `json:"nodeshell" toml:"nodeshell"`
 	KubeConfig KubeConfig `json:"kubeconfig" toml:"kubeconfig"`
 	History    History    `json:"history" toml:"history"`
+	Scale      Scale      `json:"scale" toml:"scale"`
+	Prompt     Prompt     `json:"prompt" toml:"prompt"`
 
 	NamespaceAlias []NamespaceAlias `json:"namespace_alias" toml:"namespace_alias"`
 }
@@ -44,6 +57,53 @@ type NodeShell struct {
 type KubeConfig struct {
 	Root  string            `json:"root" toml:"root"`
 	Alias map[string]string `json:"alias" toml:"alias"`
+
+	// AliasPath stores the aliases managed by command line
+	AliasPath string `json:"alias_path" toml:"alias_path"`
+
+	// MetadataPath stores the tags and descriptions of kubeconfigs
+	MetadataPath string `json:"metadata_path" toml:"metadata_path"`
+
+	Encryption Encryption `json:"encryption" toml:"encryption"`
+
+	Sync Sync `json:"sync" toml:"sync"`
+}
+
+type Sync struct {
+	// StatePath records the kubeconfigs created by sync, so that the manually
+	// created ones are never touched.
+	StatePath string `json:"state_path" toml:"state_path"`
+
+	Sources []*SyncSource `json:"sources" toml:"sources"`
+}
+
+// SyncSource is where to pull kubeconfigs from, the kubeconfigs are stored in
+// the subtree `<kubeconfig.root>/<name>`.
+type SyncSource struct {
+	Name string `json:"name" toml:"name"`
+
+	// Dir is a directory containing kubeconfig files.
+	Dir string `json:"dir" toml:"dir"`
+
+	// Command prints kubeconfig YAML documents, separated by "---". Each
+	// document is named by its current context.
+	Command []string `json:"command" toml:"command"`
+}
+
+type Encryption struct {
+	Enable bool `json:"enable" toml:"enable"`
+
+	// KeyPath stores the salt and checksum of the passphrase-derived key
+	KeyPath string `json:"key_path" toml:"key_path"`
+
+	// RuntimeDir is deprecated, use the top-level `runtime_dir` instead. It is
+	// still read for compatibility.
+	RuntimeDir string `json:"runtime_dir,omitempty" toml:"runtime_dir"`
+
+	// KeyCacheMinutes is the timeout to cache the key in runtime dir, 0 means
+	// no cache (default). The cached key is stored in plain text, only
+	// protected by the permission of runtime dir.
+	KeyCacheMinutes int `json:"key_cache_minutes" toml:"key_cache_minutes"`
 }
 
 type History struct {
@@ -51,10 +111,92 @@ type History struct {
 	Max  int    `json:"max" toml:"max"`
 }
 
+type Scale struct {
+	Min int `json:"min" toml:"min"`
+	// Max <= 0 means no limit
+	Max int `json:"max" toml:"max"`
+}
+
+type Prompt struct {
+	// Format supports placeholders "{config}", "{ns}", "{context}" and
+	// "{env}".
+	Format string `json:"format" toml:"format"`
+
+	// Color is the default color of prompt, "none" means no color.
+	Color string `json:"color" toml:"color"`
+
+	// EnvTag is the tag of kubeconfig metadata to read the environment from.
+	EnvTag string `json:"env_tag" toml:"env_tag"`
+
+	// EnvColors maps the environments to colors, for example `prod` to `red`.
+	EnvColors map[string]string `json:"env_colors" toml:"env_colors"`
+}
+
+// PromptColors are the color names supported by prompt.
+var PromptColors = []string{"none", "black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
 type NamespaceAlias struct {
 	Configs    []string `json:"configs" toml:"configs"`
 	Pattern    []string `json:"pattern" toml:"pattern"`
 	Namespaces []string `json:"namespaces" toml:"namespaces"`
+
+	// Short maps short names to namespaces, for example `pay` to
+	// `payments-backend-prod`.
+	Short map[string]string `json:"short" toml:"short"`
+
+	// Default is the namespace to use when switching to the kubeconfig.
+	Default string `json:"default" toml:"default"`
+}
+
+// Match reports whether the alias applies to the kubeconfig name.
+func (a *NamespaceAlias) Match(name string) bool {
+	for _, pattern := range a.Pattern {
+		// The pattern is validated when loading config
+		match, _ := filepath.Match(pattern, name)
+		if match {
+			return true
+		}
+	}
+	for _, configName := range a.Configs {
+		if configName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchNamespaceAliases returns the namespace aliases that apply to the
+// kubeconfig name, in config order.
+func (c *Config) MatchNamespaceAliases(name string) []*NamespaceAlias {
+	var aliases []*NamespaceAlias
+	for i := range c.NamespaceAlias {
+		if c.NamespaceAlias[i].Match(name) {
+			aliases = append(aliases, &c.NamespaceAlias[i])
+		}
+	}
+	return aliases
+}
+
+// ExpandNamespace expands the short name of namespace for the kubeconfig, if
+// it is not a short name, returns it directly.
+func (c *Config) ExpandNamespace(name, ns string) string {
+	for _, alias := range c.MatchNamespaceAliases(name) {
+		if long, ok := alias.Short[ns]; ok {
+			return long
+		}
+	}
+	return ns
+}
+
+// DefaultNamespace returns the namespace to use when switching to the
+// kubeconfig, empty if not configured.
+func (c *Config) DefaultNamespace(name string) string {
+	for _, alias := range c.MatchNamespaceAliases(name) {
+		if alias.Default != "" {
+			return alias.Default
+		}
+	}
+	return ""
 }
 
 //go:embed defaults.toml
@@ -117,6 +259,14 @@ func load(path string, useDefault bool) (*Config, error) {
 	return &cfg, nil
 }
 
+func defaultRuntimeDir() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir != "" {
+		return filepath.Join(dir, "kubewrap")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("kubewrap-%d", os.Getuid()))
+}
+
 func (c *Config) normalize() error {
 	if len(c.Command) == 0 {
 		c.Command = defaults.Command
@@ -130,6 +280,16 @@ func (c *Config) normalize() error {
 		c.Editor = "vim"
 	}
 
+	if len(c.EditorExt) == 0 {
+		c.EditorExt = defaults.EditorExt
+	}
+	if !strings.HasPrefix(c.EditorExt, ".") {
+		c.EditorExt = "." + c.EditorExt
+	}
+	if strings.ContainsAny(c.EditorExt, `/\`) {
+		return errors.New("`editor_ext` is invalid")
+	}
+
 	if len(c.SourceFilePath) == 0 {
 		c.SourceFilePath = defaults.SourceFilePath
 	}
@@ -138,6 +298,17 @@ func (c *Config) normalize() error {
 		return errors.New("`source_file_path` is not absolute")
 	}
 
+	if len(c.RuntimeDir) == 0 {
+		c.RuntimeDir = c.KubeConfig.Encryption.RuntimeDir
+	}
+	if len(c.RuntimeDir) == 0 {
+		c.RuntimeDir = defaultRuntimeDir()
+	}
+	c.RuntimeDir = os.ExpandEnv(c.RuntimeDir)
+	if !filepath.IsAbs(c.RuntimeDir) {
+		return errors.New("`runtime_dir` is not absolute")
+	}
+
 	if len(c.Kubectl.Name) == 0 {
 		c.Kubectl.Name = defaults.Kubectl.Name
 	}
@@ -160,6 +331,62 @@ func (c *Config) normalize() error {
 		return errors.New("`kubeconfig.root` is not absolute")
 	}
 
+	if len(c.KubeConfig.AliasPath) == 0 {
+		c.KubeConfig.AliasPath = defaults.KubeConfig.AliasPath
+	}
+	c.KubeConfig.AliasPath = os.ExpandEnv(c.KubeConfig.AliasPath)
+	if !filepath.IsAbs(c.KubeConfig.AliasPath) {
+		return errors.New("`kubeconfig.alias_path` is not absolute")
+	}
+
+	if len(c.KubeConfig.MetadataPath) == 0 {
+		c.KubeConfig.MetadataPath = defaults.KubeConfig.MetadataPath
+	}
+	c.KubeConfig.MetadataPath = os.ExpandEnv(c.KubeConfig.MetadataPath)
+	if !filepath.IsAbs(c.KubeConfig.MetadataPath) {
+		return errors.New("`kubeconfig.metadata_path` is not absolute")
+	}
+
+	if len(c.KubeConfig.Sync.StatePath) == 0 {
+		c.KubeConfig.Sync.StatePath = defaults.KubeConfig.Sync.StatePath
+	}
+	c.KubeConfig.Sync.StatePath = os.ExpandEnv(c.KubeConfig.Sync.StatePath)
+	if !filepath.IsAbs(c.KubeConfig.Sync.StatePath) {
+		return errors.New("`kubeconfig.sync.state_path` is not absolute")
+	}
+	sourceNames := make(map[string]struct{}, len(c.KubeConfig.Sync.Sources))
+	for i, src := range c.KubeConfig.Sync.Sources {
+		if src.Name == "" || strings.ContainsAny(src.Name, `/\`) || src.Name == "." || src.Name == ".." {
+			return fmt.Errorf("`kubeconfig.sync.sources[%d].name` is invalid", i)
+		}
+		if _, ok := sourceNames[src.Name]; ok {
+			return fmt.Errorf("`kubeconfig.sync.sources[%d].name` %q is duplicate", i, src.Name)
+		}
+		sourceNames[src.Name] = struct{}{}
+
+		if (src.Dir == "") == (len(src.Command) == 0) {
+			return fmt.Errorf("`kubeconfig.sync.sources[%d]` should have exactly one of `dir` and `command`", i)
+		}
+		if src.Dir != "" {
+			src.Dir = os.ExpandEnv(src.Dir)
+			if !filepath.IsAbs(src.Dir) {
+				return fmt.Errorf("`kubeconfig.sync.sources[%d].dir` is not absolute", i)
+			}
+		}
+	}
+
+	if len(c.KubeConfig.Encryption.KeyPath) == 0 {
+		c.KubeConfig.Encryption.KeyPath = defaults.KubeConfig.Encryption.KeyPath
+	}
+	c.KubeConfig.Encryption.KeyPath = os.ExpandEnv(c.KubeConfig.Encryption.KeyPath)
+	if !filepath.IsAbs(c.KubeConfig.Encryption.KeyPath) {
+		return errors.New("`kubeconfig.encryption.key_path` is not absolute")
+	}
+
+	if c.KubeConfig.Encryption.KeyCacheMinutes < 0 {
+		return errors.New("`kubeconfig.encryption.key_cache_minutes` should be >= 0")
+	}
+
 	if len(c.History.Path) == 0 {
 		c.History.Path = defaults.History.Path
 	}
@@ -178,5 +405,47 @@ func (c *Config) normalize() error {
 		return fmt.Errorf("`history.max` is too large, should be <= %d", maxConfigHistoryMax)
 	}
 
+	for i, alias := range c.NamespaceAlias {
+		for _, pattern := range alias.Pattern {
+			_, err := filepath.Match(pattern, "")
+			if err != nil {
+				return fmt.Errorf("`namespace_alias[%d].pattern` %q is invalid: %w", i, pattern, err)
+			}
+		}
+		for short, long := range alias.Short {
+			if short == "" || long == "" {
+				return fmt.Errorf("`namespace_alias[%d].short` has empty name", i)
+			}
+		}
+	}
+
+	if len(c.Prompt.Format) == 0 {
+		c.Prompt.Format = defaults.Prompt.Format
+	}
+	if len(c.Prompt.Color) == 0 {
+		c.Prompt.Color = defaults.Prompt.Color
+	}
+	if !slices.Contains(PromptColors, c.Prompt.Color) {
+		return fmt.Errorf("`prompt.color` %q is invalid", c.Prompt.Color)
+	}
+	if len(c.Prompt.EnvTag) == 0 {
+		c.Prompt.EnvTag = defaults.Prompt.EnvTag
+	}
+	if c.Prompt.EnvColors == nil {
+		c.Prompt.EnvColors = defaults.Prompt.EnvColors
+	}
+	for env, color := range c.Prompt.EnvColors {
+		if !slices.Contains(PromptColors, color) {
+			return fmt.Errorf("`prompt.env_colors.%s` %q is invalid", env, color)
+		}
+	}
+
+	if c.Scale.Min < 0 {
+		return errors.New("`scale.min` should be >= 0")
+	}
+	if c.Scale.Max > 0 && c.Scale.Max < c.Scale.Min {
+		return errors.New("`scale.max` should be >= `scale.min`")
+	}
+
 	return nil
 }
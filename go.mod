@@ -7,6 +7,9 @@ require (
 	github.com/fatih/color v1.18.0
 	github.com/icza/backscanner v0.0.0-20241124160932-dff01ac50250
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -14,5 +17,5 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.25.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 )
@@ -1,13 +1,50 @@
 package hack
 
 import (
-	_ "embed"
+	"embed"
+	"fmt"
 	"strings"
 )
 
 //go:embed kw.sh
 var bash string
 
+//go:embed prompt
+var prompts embed.FS
+
 func GetBash(name string) string {
 	return strings.ReplaceAll(bash, "{{name}}", name)
 }
+
+// GetPrompt returns the prompt snippet. The kind can be "starship", "p10k" or
+// "ps1".
+func GetPrompt(kind, shell string) (string, error) {
+	var name string
+	switch kind {
+	case "starship":
+		name = "starship.toml"
+
+	case "p10k":
+		if shell != "zsh" {
+			return "", fmt.Errorf("powerlevel10k only supports zsh, not %s", shell)
+		}
+		name = "p10k.zsh"
+
+	case "ps1":
+		switch shell {
+		case "bash", "zsh":
+			name = fmt.Sprintf("ps1.%s", shell)
+		default:
+			return "", fmt.Errorf("ps1 prompt does not support %s", shell)
+		}
+
+	default:
+		return "", fmt.Errorf("unknown prompt type: %s", kind)
+	}
+
+	data, err := prompts.ReadFile("prompt/" + name)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
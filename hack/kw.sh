@@ -1,3 +1,7 @@
+# Identify the shell session, it is not exported, so that every shell (including
+# the nested ones) has its own session.
+__kubewrap_session_id="$$-$(date +%s)"
+
 function {{name}}() {
   local NEED_SOURCE_CODE=302
   local DEFAULT_EXECUTABLE_PATH="kubewrap"
@@ -15,7 +19,7 @@ function {{name}}() {
     executable_path="$DEFAULT_EXECUTABLE_PATH"
   fi
 
-  $executable_path "${opts[@]}"
+  KUBEWRAP_SESSION_ID="$__kubewrap_session_id" $executable_path "${opts[@]}"
   local exit_code=$?
   if [[ $exit_code -ne 0 ]]; then
     return $exit_code
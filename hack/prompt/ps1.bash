@@ -0,0 +1,11 @@
+# Source this after the init script, the current kubeconfig and namespace are
+# shown before the prompt.
+function __kubewrap_prompt() {
+  local text
+  text="$("${KUBEWRAP_EXECUTABLE_PATH:-kubewrap}" prompt --shell bash)"
+  [[ -n "$text" ]] && echo "[$text] "
+}
+
+if [[ "$PS1" != *'__kubewrap_prompt'* ]]; then
+  PS1='$(__kubewrap_prompt)'"$PS1"
+fi
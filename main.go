@@ -7,11 +7,21 @@ import (
 
 	"github.com/fioncat/kubewrap/cmd/config"
 	"github.com/fioncat/kubewrap/cmd/cp"
+	"github.com/fioncat/kubewrap/cmd/ctx"
+	"github.com/fioncat/kubewrap/cmd/describe"
+	editcmd "github.com/fioncat/kubewrap/cmd/edit"
+	"github.com/fioncat/kubewrap/cmd/events"
 	"github.com/fioncat/kubewrap/cmd/exec"
+	"github.com/fioncat/kubewrap/cmd/get"
+	historycmd "github.com/fioncat/kubewrap/cmd/history"
 	initcmd "github.com/fioncat/kubewrap/cmd/init"
 	"github.com/fioncat/kubewrap/cmd/login"
+	"github.com/fioncat/kubewrap/cmd/nav"
 	"github.com/fioncat/kubewrap/cmd/ns"
+	"github.com/fioncat/kubewrap/cmd/park"
+	"github.com/fioncat/kubewrap/cmd/prompt"
 	"github.com/fioncat/kubewrap/cmd/restart"
+	"github.com/fioncat/kubewrap/cmd/rollout"
 	"github.com/fioncat/kubewrap/cmd/scale"
 	"github.com/fioncat/kubewrap/cmd/setimage"
 	"github.com/fioncat/kubewrap/cmd/show"
@@ -67,11 +77,23 @@ func main() {
 
 	c.AddCommand(config.New())
 	c.AddCommand(cp.New())
+	c.AddCommand(ctx.New())
+	c.AddCommand(describe.New())
+	c.AddCommand(editcmd.New())
+	c.AddCommand(events.New())
 	c.AddCommand(exec.New())
+	c.AddCommand(get.New())
+	c.AddCommand(historycmd.New())
 	c.AddCommand(initcmd.New())
 	c.AddCommand(login.New())
+	c.AddCommand(nav.NewBack())
+	c.AddCommand(nav.NewForward())
 	c.AddCommand(ns.New())
+	c.AddCommand(park.New())
+	c.AddCommand(park.NewUnpark())
+	c.AddCommand(prompt.New())
 	c.AddCommand(restart.New())
+	c.AddCommand(rollout.New())
 	c.AddCommand(scale.New())
 	c.AddCommand(setimage.New())
 	c.AddCommand(show.New())
@@ -0,0 +1,63 @@
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// tempPattern matches the temporary file names created by Write, the "*" in
+// pattern of os.CreateTemp is replaced by a random number.
+var tempPattern = regexp.MustCompile(`^\..+\.tmp[0-9]+$`)
+
+// IsTemp reports whether the file name is a temporary file created by Write,
+// which is left by an interrupted write.
+func IsTemp(name string) bool {
+	return tempPattern.MatchString(name)
+}
+
+// Write writes data to a temporary file in the same directory and then renames
+// it to path, so that a crash in the middle of writing won't corrupt the file.
+func Write(path string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := file.Name()
+
+	err = write(file, data, perm)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+func write(file *os.File, data []byte, perm os.FileMode) error {
+	defer file.Close()
+
+	err := file.Chmod(perm)
+	if err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Diff uses the `diff` command to generate unified diff between two contents.
+// Return empty string if they are the same.
+func Diff(oldName string, oldData []byte, newName string, newData []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "kubewrap_diff_")
+	if err != nil {
+		return "", fmt.Errorf("create diff dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath := filepath.Join(dir, "old")
+	err = os.WriteFile(oldPath, oldData, 0600)
+	if err != nil {
+		return "", fmt.Errorf("write diff file: %w", err)
+	}
+	newPath := filepath.Join(dir, "new")
+	err = os.WriteFile(newPath, newData, 0600)
+	if err != nil {
+		return "", fmt.Errorf("write diff file: %w", err)
+	}
+
+	var outputBuf bytes.Buffer
+	cmd := exec.Command("diff", "-u",
+		"--label", oldName, "--label", newName,
+		oldPath, newPath)
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		// diff exits with code 1 when there are differences
+		if exitError, ok := err.(*exec.ExitError); !ok || exitError.ExitCode() != 1 {
+			return "", fmt.Errorf("run diff command: %w", err)
+		}
+	}
+
+	return outputBuf.String(), nil
+}
+
+// Print prints the diff content with colors.
+func Print(diff string) {
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = color.New(color.Bold).Sprint(line)
+		case strings.HasPrefix(line, "@@"):
+			line = color.CyanString(line)
+		case strings.HasPrefix(line, "+"):
+			line = color.GreenString(line)
+		case strings.HasPrefix(line, "-"):
+			line = color.RedString(line)
+		}
+		fmt.Println(line)
+	}
+}
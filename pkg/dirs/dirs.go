@@ -3,13 +3,22 @@ package dirs
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"syscall"
 )
 
+// DirPerm is the permission of directories created by kubewrap, they may
+// contain credentials, so only the current user can access them.
+const DirPerm os.FileMode = 0700
+
+// FilePerm is the permission of credential files written by kubewrap.
+const FilePerm os.FileMode = 0600
+
 func EnsureCreate(path string) error {
 	stat, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			err = os.MkdirAll(path, os.ModePerm)
+			err = os.MkdirAll(path, DirPerm)
 			if err != nil {
 				return fmt.Errorf("mkdir dir: %w", err)
 			}
@@ -24,3 +33,40 @@ func EnsureCreate(path string) error {
 
 	return nil
 }
+
+// EnsurePrivate is like EnsureCreate, but the directory must be private to the
+// current user, see CheckPrivate. It is used for the directories in shared
+// places (such as /tmp), which could be created by other users in advance.
+func EnsurePrivate(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), DirPerm)
+	if err != nil {
+		return fmt.Errorf("mkdir parent dir: %w", err)
+	}
+	err = os.Mkdir(path, DirPerm)
+	if err != nil && !os.IsExist(err) {
+		return fmt.Errorf("mkdir dir: %w", err)
+	}
+	return CheckPrivate(path)
+}
+
+// CheckPrivate checks that the path is a directory (not a symlink) owned by the
+// current user, and only the owner can access it.
+func CheckPrivate(path string) error {
+	stat, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("check dir stat: %w", err)
+	}
+	if stat.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("refuse to use %s: it is a symlink", path)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("refuse to use %s: it is not a directory", path)
+	}
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok && int(sys.Uid) != os.Getuid() {
+		return fmt.Errorf("refuse to use %s: it is owned by uid %d, not the current user", path, sys.Uid)
+	}
+	if perm := stat.Mode().Perm(); perm != DirPerm {
+		return fmt.Errorf("refuse to use %s: its permission is %v, should be %v", path, perm, os.FileMode(DirPerm))
+	}
+	return nil
+}
@@ -5,19 +5,34 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/fioncat/kubewrap/config"
 )
 
+var ErrNotChanged = errors.New("edit content not changed")
+
+// Edit opens the editor to edit initData, the edit file uses the extension
+// `editor_ext` in config, so that the editor can enable syntax highlighting.
 func Edit(cfg *config.Config, initData []byte) ([]byte, error) {
-	path, err := createEditFile(initData)
+	// The editor can be a wrapper with extra args, such as "code --wait"
+	editorArgs, err := parseCommand(cfg.Editor)
+	if err != nil {
+		return nil, fmt.Errorf("parse editor command: %w", err)
+	}
+	if len(editorArgs) == 0 {
+		return nil, errors.New("editor command is empty")
+	}
+
+	path, err := createEditFile(initData, cfg.EditorExt)
 	if err != nil {
 		return nil, err
 	}
+	defer os.Remove(path)
 
-	cmd := exec.Command(cfg.Editor, path)
+	editorArgs = append(editorArgs, path)
+	cmd := exec.Command(editorArgs[0], editorArgs[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
@@ -32,23 +47,89 @@ func Edit(cfg *config.Config, initData []byte) ([]byte, error) {
 		return nil, fmt.Errorf("read edit file: %w", err)
 	}
 
-	err = os.Remove(path)
-	if err != nil {
-		return nil, fmt.Errorf("remove edit file: %w", err)
-	}
-
 	if reflect.DeepEqual(data, initData) {
-		return nil, errors.New("edit content not changed")
+		return nil, ErrNotChanged
 	}
 
 	return data, nil
 }
 
-func createEditFile(initData []byte) (string, error) {
-	path := filepath.Join(os.TempDir(), "kubewrap_edit.yaml")
-	err := os.WriteFile(path, initData, 0644)
+// Keep writes data to a new edit file and keeps it, so that the edits are not
+// lost when they cannot be applied. Returns the path of file.
+func Keep(cfg *config.Config, data []byte) (string, error) {
+	return createEditFile(data, cfg.EditorExt)
+}
+
+// createEditFile creates a unique edit file, the file may contain credentials,
+// so only the current user can access it (`os.CreateTemp` uses 0600).
+func createEditFile(initData []byte, ext string) (string, error) {
+	file, err := os.CreateTemp("", "kubewrap_edit_*"+ext)
 	if err != nil {
+		return "", fmt.Errorf("create edit file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(initData)
+	if err != nil {
+		os.Remove(file.Name())
 		return "", fmt.Errorf("write edit file: %w", err)
 	}
-	return path, nil
+
+	return file.Name(), nil
+}
+
+// parseCommand splits the command string into args like shell, supports single
+// quotes, double quotes and backslash escapes.
+func parseCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	var inArg bool
+	var quote rune
+	var escape bool
+
+	for _, ch := range s {
+		switch {
+		case escape:
+			cur.WriteRune(ch)
+			escape = false
+
+		case ch == '\\' && quote != '\'':
+			escape = true
+			inArg = true
+
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+				continue
+			}
+			cur.WriteRune(ch)
+
+		case ch == '\'' || ch == '"':
+			quote = ch
+			inArg = true
+
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+
+		default:
+			cur.WriteRune(ch)
+			inArg = true
+		}
+	}
+
+	if escape {
+		return nil, errors.New("unexpected end after backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote %q", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+
+	return args, nil
 }
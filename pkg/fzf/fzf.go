@@ -14,6 +14,15 @@ var ErrCanceled = errors.New("fzf: canceled by user")
 const ExitCodeCanceled = 130
 
 func Search(items []string) (int, error) {
+	return search(items)
+}
+
+// SearchQuery is like Search, but the fzf is started with the query.
+func SearchQuery(items []string, query string) (int, error) {
+	return search(items, "--query", query)
+}
+
+func search(items []string, args ...string) (int, error) {
 	var inputBuf bytes.Buffer
 	inputBuf.Grow(len(items))
 	for _, item := range items {
@@ -21,7 +30,7 @@ func Search(items []string) (int, error) {
 	}
 
 	var outputBuf bytes.Buffer
-	cmd := exec.Command("fzf")
+	cmd := exec.Command("fzf", args...)
 	cmd.Stdin = &inputBuf
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = &outputBuf
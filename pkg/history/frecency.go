@@ -0,0 +1,98 @@
+package history
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scores is the frecency (frequency plus recency) of names, higher is more
+// likely to be selected.
+type Scores map[string]float64
+
+// ConfigScores computes the frecency of kubeconfigs from records.
+func ConfigScores(records []*Record) Scores {
+	return computeScores(records, func(record *Record) string {
+		return record.Name
+	})
+}
+
+// NamespaceScores computes the frecency of namespaces in kubeconfig name from
+// records.
+func NamespaceScores(records []*Record, name string) Scores {
+	return computeScores(records, func(record *Record) string {
+		if record.Name != name {
+			return ""
+		}
+		return record.Namespace
+	})
+}
+
+func computeScores(records []*Record, key func(*Record) string) Scores {
+	now := time.Now()
+	scores := make(Scores)
+	for _, record := range records {
+		k := key(record)
+		if k == "" {
+			continue
+		}
+		scores[k] += recencyWeight(now.Sub(time.Unix(record.Timestamp, 0)))
+	}
+	return scores
+}
+
+// recencyWeight is the same as zoxide, the recent access weighs more.
+func recencyWeight(age time.Duration) float64 {
+	switch {
+	case age < time.Hour:
+		return 4
+	case age < time.Hour*24:
+		return 2
+	case age < time.Hour*24*7:
+		return 0.5
+	default:
+		return 0.25
+	}
+}
+
+// Sort sorts items by frecency in descending order, the items with the same
+// score keep their original order.
+func Sort[T any](items []T, key func(T) string, scores Scores) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return scores[key(items[i])] > scores[key(items[j])]
+	})
+}
+
+// BestMatch returns the item with the highest frecency that fuzzy matches the
+// query. The query matches if all its chars appear in the item in order,
+// case-insensitively.
+func BestMatch(query string, items []string, scores Scores) (string, bool) {
+	var (
+		best      string
+		bestScore float64
+		found     bool
+	)
+	for _, item := range items {
+		if !fuzzyMatch(query, item) {
+			continue
+		}
+		score := scores[item]
+		if !found || score > bestScore || (score == bestScore && len(item) < len(best)) {
+			best, bestScore, found = item, score, true
+		}
+	}
+	return best, found
+}
+
+func fuzzyMatch(query, s string) bool {
+	query = strings.ToLower(query)
+	s = strings.ToLower(s)
+	for _, c := range query {
+		idx := strings.IndexRune(s, c)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(string(c)):]
+	}
+	return true
+}
@@ -1,20 +1,36 @@
 package history
 
 type Manager interface {
-	Add(name, namespace string)
+	Add(name, namespace, context string)
 	GetLastName(current string) *string
 	GetLastNamespace(name, current string) *string
 
 	DeleteByName(name string)
+	// DeleteFunc deletes the records that fn returns true.
+	DeleteFunc(fn func(*Record) bool)
+	Rename(oldName, newName string)
 	DeleteAll()
 
+	// List returns the latest max records.
 	List() []*Record
+	// ListAll returns all the records in file, not limited by the max records.
+	ListAll() ([]*Record, error)
+	// ListSession returns all the records of the shell session, not limited by
+	// the max records.
+	ListSession(session string) ([]*Record, error)
 
 	Save() error
 }
 
 type Record struct {
-	Timestamp int64
-	Name      string
-	Namespace string
+	Timestamp int64  `json:"timestamp"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Context is the context selected by session, empty means the
+	// current-context in kubeconfig file.
+	Context string `json:"context,omitempty"`
+
+	// Session is the shell session ID that created the record.
+	Session string `json:"session,omitempty"`
 }
@@ -1,33 +1,78 @@
 package history
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
 	"github.com/fioncat/kubewrap/pkg/dirs"
 	"github.com/icza/backscanner"
 )
 
+// envSession is the shell session ID, set by the init script.
+const envSession = "KUBEWRAP_SESSION_ID"
+
+// linePrefixV2 marks the versioned line format, the data is a json object. The
+// legacy format is "<timestamp> <name> [namespace] [@context]".
+const linePrefixV2 = "v2 "
+
+// The history file is compacted when there are more than `max` records to be
+// dropped, see compact.
+
+// sessionActiveTime is the time since the last record of a session, in which
+// the session is considered active, its records are kept for navigation.
+const sessionActiveTime = time.Hour * 24
+
 type manager struct {
-	path    string
+	path string
+	max  int
+
+	session string
+
 	records []*Record
+
+	// pending records are appended to file when saving
+	pending []*Record
+	// ops are the modifications to the records in file, they require to
+	// rewrite the file
+	ops []func([]*Record) []*Record
+
+	// dropped is the number of records in file that would be dropped by
+	// compaction, used to decide when to compact
+	dropped int
+}
+
+// CurrentSession returns the shell session ID, empty if the init script is not
+// sourced.
+func CurrentSession() string {
+	return os.Getenv(envSession)
 }
 
 func NewManager(path string, max int) (Manager, error) {
-	mgr := &manager{path: path}
-	err := mgr.scan(max)
+	mgr := &manager{
+		path:    path,
+		max:     max,
+		session: CurrentSession(),
+	}
+	err := mgr.scan()
 	if err != nil {
 		return nil, err
 	}
 	return mgr, nil
 }
 
-func (m *manager) scan(max int) error {
+// scan reads the latest records from the end of file. The records are stored
+// in chronological order.
+func (m *manager) scan() error {
 	file, err := os.Open(m.path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -42,6 +87,10 @@ func (m *manager) scan(max int) error {
 		return err
 	}
 
+	var (
+		records []*Record
+		lines   int
+	)
 	scanner := backscanner.New(file, int(stat.Size()))
 	for {
 		line, _, err := scanner.Line()
@@ -56,24 +105,45 @@ func (m *manager) scan(max int) error {
 		if len(line) == 0 {
 			continue
 		}
+		lines++
 
-		record, ok := m.parse(line)
+		record, ok := parse(line)
 		if !ok {
 			continue
 		}
-		m.records = append(m.records, record)
-		if len(m.records) >= max {
-			break
-		}
+		records = append(records, record)
 	}
+	slices.Reverse(records)
 
+	// The broken lines are dropped by compaction as well
+	m.dropped = lines - len(compact(records, m.max))
+	if len(records) > m.max {
+		records = records[len(records)-m.max:]
+	}
+	m.records = records
 	return nil
 }
 
-func (m *manager) parse(line string) (*Record, bool) {
+func parse(line string) (*Record, bool) {
+	if strings.HasPrefix(line, linePrefixV2) {
+		var record Record
+		err := json.Unmarshal([]byte(strings.TrimPrefix(line, linePrefixV2)), &record)
+		if err != nil || record.Timestamp == 0 || record.Name == "" {
+			return nil, false
+		}
+		return &record, true
+	}
+	return parseLegacy(line)
+}
+
+// contextPrefix marks the context field in legacy history line, the namespace
+// cannot start with it.
+const contextPrefix = "@"
+
+func parseLegacy(line string) (*Record, bool) {
 	fields := strings.Fields(line)
 
-	if len(fields) != 2 && len(fields) != 3 {
+	if len(fields) < 2 || len(fields) > 4 {
 		return nil, false
 	}
 
@@ -90,24 +160,44 @@ func (m *manager) parse(line string) (*Record, bool) {
 		return nil, false
 	}
 
-	var namespace string
-	if len(fields) == 3 {
-		namespace = fields[2]
+	var namespace, context string
+	for _, field := range fields[2:] {
+		if strings.HasPrefix(field, contextPrefix) {
+			context = strings.TrimPrefix(field, contextPrefix)
+			continue
+		}
+		if namespace != "" {
+			return nil, false
+		}
+		namespace = field
 	}
 
 	return &Record{
 		Timestamp: timestamp,
 		Name:      name,
 		Namespace: namespace,
+		Context:   context,
 	}, true
 }
 
-func (m *manager) Add(name, namespace string) {
-	m.records = append(m.records, &Record{
+func format(record *Record) (string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return linePrefixV2 + string(data) + "\n", nil
+}
+
+func (m *manager) Add(name, namespace, context string) {
+	record := &Record{
 		Timestamp: time.Now().Unix(),
 		Name:      name,
 		Namespace: namespace,
-	})
+		Context:   context,
+		Session:   m.session,
+	}
+	m.records = append(m.records, record)
+	m.pending = append(m.pending, record)
 }
 
 func (m *manager) GetLastName(current string) *string {
@@ -133,18 +223,47 @@ func (m *manager) GetLastNamespace(name, current string) *string {
 }
 
 func (m *manager) DeleteByName(name string) {
-	newRecords := make([]*Record, 0)
-	for _, record := range m.records {
-		if record.Name == name {
-			continue
+	m.DeleteFunc(func(record *Record) bool {
+		return record.Name == name
+	})
+}
+
+func (m *manager) DeleteFunc(fn func(*Record) bool) {
+	m.modify(func(records []*Record) []*Record {
+		newRecords := make([]*Record, 0, len(records))
+		for _, record := range records {
+			if fn(record) {
+				continue
+			}
+			newRecords = append(newRecords, record)
 		}
-		newRecords = append(newRecords, record)
-	}
-	m.records = newRecords
+		return newRecords
+	})
+}
+
+func (m *manager) Rename(oldName, newName string) {
+	m.modify(func(records []*Record) []*Record {
+		for _, record := range records {
+			if record.Name == oldName {
+				record.Name = newName
+			}
+		}
+		return records
+	})
 }
 
 func (m *manager) DeleteAll() {
-	m.records = nil
+	m.modify(func([]*Record) []*Record {
+		return nil
+	})
+}
+
+// modify applies op to the records in memory now, and to the records in file
+// when saving.
+func (m *manager) modify(op func([]*Record) []*Record) {
+	m.records = op(m.records)
+	m.pending = op(m.pending)
+	m.ops = append(m.ops, op)
 }
 
 func (m *manager) List() []*Record {
@@ -152,32 +271,204 @@ func (m *manager) List() []*Record {
 }
 
 func (m *manager) Save() error {
+	if len(m.pending) == 0 && len(m.ops) == 0 {
+		return nil
+	}
+
 	err := dirs.EnsureCreate(filepath.Dir(m.path))
 	if err != nil {
 		return fmt.Errorf("ensure history directory: %w", err)
 	}
 
-	file, err := os.Create(m.path)
+	unlock, err := m.lock()
 	if err != nil {
-		return fmt.Errorf("create history file: %w", err)
+		return err
+	}
+	defer unlock()
+
+	if len(m.ops) > 0 || m.dropped > m.max {
+		err = m.rewrite()
+	} else {
+		err = m.append()
+	}
+	if err != nil {
+		return err
+	}
+
+	m.pending = nil
+	m.ops = nil
+	return nil
+}
+
+// append writes the pending records to the end of file, so that the records
+// written by other processes are kept.
+func (m *manager) append() error {
+	file, err := os.OpenFile(m.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("open history file: %w", err)
 	}
 	defer file.Close()
 
-	for _, record := range m.records {
-		sb := strings.Builder{}
-		sb.WriteString(fmt.Sprint(record.Timestamp))
-		sb.WriteByte(' ')
-		sb.WriteString(record.Name)
-		if record.Namespace != "" {
-			sb.WriteByte(' ')
-			sb.WriteString(record.Namespace)
-		}
-		sb.WriteByte('\n')
-		_, err = file.WriteString(sb.String())
+	var sb strings.Builder
+	for _, record := range m.pending {
+		line, err := format(record)
 		if err != nil {
-			return fmt.Errorf("write history file: %w", err)
+			return err
 		}
+		sb.WriteString(line)
+	}
+	_, err = file.WriteString(sb.String())
+	if err != nil {
+		return fmt.Errorf("write history file: %w", err)
 	}
 
 	return nil
 }
+
+// rewrite reads all records in file, applies the modifications and compacts
+// them to the latest `max` records. The file is replaced atomically.
+func (m *manager) rewrite() error {
+	records, err := m.readAll()
+	if err != nil {
+		return err
+	}
+	for _, op := range m.ops {
+		records = op(records)
+	}
+	records = append(records, m.pending...)
+	records = compact(records, m.max)
+
+	var sb strings.Builder
+	for _, record := range records {
+		line, err := format(record)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(line)
+	}
+	err = atomicfile.Write(m.path, []byte(sb.String()), dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("write history file: %w", err)
+	}
+
+	m.dropped = 0
+	return nil
+}
+
+// compact keeps the latest max records. The switches in other shells should
+// not push out the records of an active session, which are used to navigate,
+// so the latest max records of each active session are kept as well.
+func compact(records []*Record, max int) []*Record {
+	if len(records) <= max {
+		return records
+	}
+
+	activeTime := time.Now().Add(-sessionActiveTime).Unix()
+	active := make(map[string]bool)
+	for _, record := range records {
+		if record.Session != "" && record.Timestamp >= activeTime {
+			active[record.Session] = true
+		}
+	}
+
+	cut := len(records) - max
+	counts := make(map[string]int)
+	for _, record := range records[cut:] {
+		counts[record.Session]++
+	}
+	keep := make([]bool, cut)
+	for i := cut - 1; i >= 0; i-- {
+		session := records[i].Session
+		if active[session] && counts[session] < max {
+			keep[i] = true
+			counts[session]++
+		}
+	}
+
+	var compacted []*Record
+	for i, record := range records[:cut] {
+		if keep[i] {
+			compacted = append(compacted, record)
+		}
+	}
+	return append(compacted, records[cut:]...)
+}
+
+// ListAll reads all the records in file, with the unsaved modifications, in
+// chronological order. Unlike List, it is not limited by max.
+func (m *manager) ListAll() ([]*Record, error) {
+	records, err := m.readAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range m.ops {
+		records = op(records)
+	}
+	return append(records, m.pending...), nil
+}
+
+// ListSession is like ListAll, but only returns the records of session.
+func (m *manager) ListSession(session string) ([]*Record, error) {
+	records, err := m.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionRecords []*Record
+	for _, record := range records {
+		if record.Session == session {
+			sessionRecords = append(sessionRecords, record)
+		}
+	}
+	return sessionRecords, nil
+}
+
+func (m *manager) readAll() ([]*Record, error) {
+	file, err := os.Open(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open history file: %w", err)
+	}
+	defer file.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		record, ok := parse(line)
+		if !ok {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read history file: %w", err)
+	}
+	return records, nil
+}
+
+// lock acquires an advisory lock to serialize the writes from different
+// processes. A separate lock file is used since the history file can be
+// replaced when rewriting.
+func (m *manager) lock() (func(), error) {
+	file, err := os.OpenFile(m.path+".lock", os.O_RDWR|os.O_CREATE, dirs.FilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("open history lock file: %w", err)
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock history file: %w", err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
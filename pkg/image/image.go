@@ -0,0 +1,106 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultRegistry = "docker.io"
+
+// legacyDefaultRegistry is the old name of defaultRegistry, it is still used in
+// some images.
+const legacyDefaultRegistry = "index.docker.io"
+
+type Reference struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func Parse(image string) *Reference {
+	var ref Reference
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		ref.Digest = image[idx+1:]
+		image = image[:idx]
+	}
+	if idx := strings.LastIndex(image, ":"); idx >= 0 && idx > strings.LastIndex(image, "/") {
+		ref.Tag = image[idx+1:]
+		image = image[:idx]
+	}
+	ref.Repository = image
+	return &ref
+}
+
+func (r *Reference) String() string {
+	s := r.Repository
+	if r.Tag != "" {
+		s = fmt.Sprintf("%s:%s", s, r.Tag)
+	}
+	if r.Digest != "" {
+		s = fmt.Sprintf("%s@%s", s, r.Digest)
+	}
+	return s
+}
+
+// IsPartial reports whether the image only contains tag or digest, such as
+// ":v1.2.3" or "@sha256:...".
+func IsPartial(image string) bool {
+	return strings.HasPrefix(image, ":") || strings.HasPrefix(image, "@")
+}
+
+// Update applies the update to the current image. If update is a partial
+// image (see `IsPartial`), the repository of current image is kept and only
+// the tag or digest is swapped, otherwise, the update is returned directly.
+func Update(current, update string) (string, error) {
+	if !IsPartial(update) {
+		return update, nil
+	}
+
+	patch := Parse(update)
+	if patch.Repository != "" || (patch.Tag == "" && patch.Digest == "") {
+		return "", fmt.Errorf("invalid image update %q, should be ':<tag>' or '@<digest>'", update)
+	}
+
+	ref := Parse(current)
+	if ref.Repository == "" {
+		return "", errors.New("current image has no repository")
+	}
+	if patch.Tag != "" {
+		ref.Tag = patch.Tag
+		// The old digest points to the old tag, drop it
+		ref.Digest = ""
+	}
+	if patch.Digest != "" {
+		ref.Digest = patch.Digest
+	}
+
+	return ref.String(), nil
+}
+
+// NormalizeRepository completes the registry and namespace of a repository
+// with docker defaults, so that "nginx", "docker.io/nginx" and
+// "docker.io/library/nginx" can be compared.
+func NormalizeRepository(repo string) string {
+	domain, path, ok := strings.Cut(repo, "/")
+	if !ok || (domain != "localhost" && !strings.ContainsAny(domain, ".:")) {
+		// No registry
+		domain, path = defaultRegistry, repo
+	}
+	if domain == legacyDefaultRegistry {
+		domain = defaultRegistry
+	}
+	if domain == defaultRegistry && !strings.Contains(path, "/") {
+		// The official images
+		path = "library/" + path
+	}
+	return fmt.Sprintf("%s/%s", domain, path)
+}
+
+// MatchRepository reports whether the image uses the repository. Tag and
+// digest in repo are ignored.
+func MatchRepository(image, repo string) bool {
+	a := NormalizeRepository(Parse(image).Repository)
+	b := NormalizeRepository(Parse(repo).Repository)
+	return a == b
+}
@@ -0,0 +1,176 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		image  string
+		expect Reference
+	}{
+		{
+			image:  "nginx",
+			expect: Reference{Repository: "nginx"},
+		},
+		{
+			image:  "nginx:1.25",
+			expect: Reference{Repository: "nginx", Tag: "1.25"},
+		},
+		{
+			image:  "localhost:5000/app:v1",
+			expect: Reference{Repository: "localhost:5000/app", Tag: "v1"},
+		},
+		{
+			image:  "localhost:5000/app",
+			expect: Reference{Repository: "localhost:5000/app"},
+		},
+		{
+			image:  "ghcr.io/org/app:v1@sha256:abc",
+			expect: Reference{Repository: "ghcr.io/org/app", Tag: "v1", Digest: "sha256:abc"},
+		},
+		{
+			image:  "app@sha256:abc",
+			expect: Reference{Repository: "app", Digest: "sha256:abc"},
+		},
+		{
+			image:  ":v2",
+			expect: Reference{Tag: "v2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref := Parse(tt.image)
+			if !reflect.DeepEqual(*ref, tt.expect) {
+				t.Fatalf("unexpected reference, expect %+v, actual %+v", tt.expect, *ref)
+			}
+			if ref.String() != tt.image {
+				t.Fatalf("unexpected string %q", ref.String())
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		update  string
+		expect  string
+		err     bool
+	}{
+		{
+			name:    "full image",
+			current: "nginx:1.25",
+			update:  "ghcr.io/org/nginx:1.26",
+			expect:  "ghcr.io/org/nginx:1.26",
+		},
+		{
+			name:    "tag",
+			current: "localhost:5000/app:v1",
+			update:  ":v2",
+			expect:  "localhost:5000/app:v2",
+		},
+		{
+			name:    "tag drops digest",
+			current: "app:v1@sha256:abc",
+			update:  ":v2",
+			expect:  "app:v2",
+		},
+		{
+			name:    "digest",
+			current: "app:v1@sha256:abc",
+			update:  "@sha256:def",
+			expect:  "app:v1@sha256:def",
+		},
+		{
+			name:    "tag and digest",
+			current: "app",
+			update:  ":v2@sha256:def",
+			expect:  "app:v2@sha256:def",
+		},
+		{
+			name:    "empty tag",
+			current: "app:v1",
+			update:  ":",
+			err:     true,
+		},
+		{
+			name:    "no current repository",
+			current: "",
+			update:  ":v2",
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image, err := Update(tt.current, tt.update)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expect error, got %q", image)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if image != tt.expect {
+				t.Fatalf("unexpected image, expect %q, actual %q", tt.expect, image)
+			}
+		})
+	}
+}
+
+func TestNormalizeRepository(t *testing.T) {
+	tests := []struct {
+		repo   string
+		expect string
+	}{
+		{"nginx", "docker.io/library/nginx"},
+		{"docker.io/nginx", "docker.io/library/nginx"},
+		{"index.docker.io/nginx", "docker.io/library/nginx"},
+		{"docker.io/library/nginx", "docker.io/library/nginx"},
+		{"bitnami/redis", "docker.io/bitnami/redis"},
+		{"index.docker.io/bitnami/redis", "docker.io/bitnami/redis"},
+		{"ghcr.io/org/app", "ghcr.io/org/app"},
+		{"quay.io/app", "quay.io/app"},
+		{"localhost/app", "localhost/app"},
+		{"localhost:5000/app", "localhost:5000/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			repo := NormalizeRepository(tt.repo)
+			if repo != tt.expect {
+				t.Fatalf("unexpected repository, expect %q, actual %q", tt.expect, repo)
+			}
+		})
+	}
+}
+
+func TestMatchRepository(t *testing.T) {
+	tests := []struct {
+		image  string
+		repo   string
+		expect bool
+	}{
+		{"nginx:1.25", "nginx", true},
+		{"docker.io/nginx:1.25", "nginx", true},
+		{"index.docker.io/library/nginx@sha256:abc", "docker.io/nginx:latest", true},
+		{"nginx:1.25", "bitnami/nginx", false},
+		{"ghcr.io/org/app:v1", "ghcr.io/org/app", true},
+		{"ghcr.io/org/app:v1", "org/app", false},
+		{"localhost:5000/app:v1", "localhost:5000/app", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image+" "+tt.repo, func(t *testing.T) {
+			if match := MatchRepository(tt.image, tt.repo); match != tt.expect {
+				t.Fatalf("unexpected match %v", match)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+)
+
+// The aliases in config file are readonly, the aliases added by command line
+// are stored in a separate state file, they can be modified by kubewrap.
+
+func loadAliasState(path string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return aliases, nil
+		}
+		return nil, fmt.Errorf("read alias file: %w", err)
+	}
+
+	err = json.Unmarshal(data, &aliases)
+	if err != nil {
+		return nil, fmt.Errorf("decode alias file: %w", err)
+	}
+	return aliases, nil
+}
+
+func (m *manager) saveAliasState() error {
+	data, err := json.MarshalIndent(m.stateAliases, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = dirs.EnsureCreate(filepath.Dir(m.aliasPath))
+	if err != nil {
+		return fmt.Errorf("ensure alias file dir: %w", err)
+	}
+	err = atomicfile.Write(m.aliasPath, data, dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("write alias file: %w", err)
+	}
+	return nil
+}
+
+func (m *manager) AddAlias(alias, target string) error {
+	if _, ok := m.configs[alias]; ok {
+		return fmt.Errorf("name %q is already used by a kubeconfig or alias", alias)
+	}
+	err := checkName(alias)
+	if err != nil {
+		return err
+	}
+	config, ok := m.configs[target]
+	if !ok {
+		return fmt.Errorf("alias target %q not found", target)
+	}
+	if config.Alias != "" {
+		return fmt.Errorf("alias target %q is an alias, please use %q directly", target, config.Alias)
+	}
+
+	m.stateAliases[alias] = target
+	err = m.saveAliasState()
+	if err != nil {
+		return err
+	}
+
+	m.configs[alias] = &KubeConfig{
+		root: m.root,
+
+		Name:  alias,
+		Alias: target,
+	}
+	return nil
+}
+
+func (m *manager) RemoveAlias(alias string) error {
+	config, ok := m.configs[alias]
+	if !ok || config.Alias == "" {
+		return fmt.Errorf("alias %q not found", alias)
+	}
+	if _, ok = m.stateAliases[alias]; !ok {
+		return fmt.Errorf("alias %q is defined in config file, please remove it from config file", alias)
+	}
+	if m.current != nil && m.current.Name == alias {
+		return errors.New("cannot remove current alias, please unuse it first")
+	}
+
+	delete(m.stateAliases, alias)
+	err := m.saveAliasState()
+	if err != nil {
+		return err
+	}
+
+	delete(m.configs, alias)
+	return nil
+}
+
+func (m *manager) ListAliases() []*KubeConfig {
+	var aliases []*KubeConfig
+	for _, config := range m.configs {
+		if config.Alias != "" {
+			aliases = append(aliases, config)
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Name < aliases[j].Name
+	})
+	return aliases
+}
+
+func (m *manager) isStateAlias(name string) bool {
+	_, ok := m.stateAliases[name]
+	return ok
+}
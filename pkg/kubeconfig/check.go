@@ -0,0 +1,186 @@
+package kubeconfig
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	CheckKindClientCert = "client-cert"
+	CheckKindCA         = "ca"
+	CheckKindToken      = "token"
+	CheckKindExec       = "exec"
+)
+
+// CheckItem is a credential found in kubeconfig file.
+type CheckItem struct {
+	Kind string `json:"kind"`
+
+	// Subject is the user or cluster name of the credential
+	Subject string `json:"subject"`
+
+	// ExpireAt is nil if the credential doesn't expire (or we cannot know)
+	ExpireAt *time.Time `json:"expire_at,omitempty"`
+
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Check parses the credentials in kubeconfig data and reports their expiry.
+// The dir is used to resolve relative file paths in kubeconfig.
+func Check(data []byte, dir string) ([]*CheckItem, error) {
+	file, err := ParseFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*CheckItem
+	for _, cluster := range file.Clusters {
+		if cluster.Cluster == nil {
+			continue
+		}
+		certData := cluster.Cluster.CertificateAuthorityData
+		if len(certData) == 0 && cluster.Cluster.CertificateAuthority == "" {
+			continue
+		}
+		item := &CheckItem{Kind: CheckKindCA, Subject: cluster.Name}
+		item.ExpireAt, err = checkCert(certData, cluster.Cluster.CertificateAuthority, dir)
+		if err != nil {
+			item.Error = err.Error()
+		}
+		items = append(items, item)
+	}
+
+	for _, user := range file.Users {
+		if user.User == nil {
+			continue
+		}
+		u := user.User
+
+		if len(u.ClientCertificateData) > 0 || u.ClientCertificate != "" {
+			item := &CheckItem{Kind: CheckKindClientCert, Subject: user.Name}
+			item.ExpireAt, err = checkCert(u.ClientCertificateData, u.ClientCertificate, dir)
+			if err != nil {
+				item.Error = err.Error()
+			}
+			items = append(items, item)
+		}
+
+		if u.Token != "" || u.TokenFile != "" {
+			item := &CheckItem{Kind: CheckKindToken, Subject: user.Name}
+			item.ExpireAt, err = checkToken(u.Token, u.TokenFile, dir)
+			if err != nil {
+				item.Error = err.Error()
+			}
+			items = append(items, item)
+		}
+
+		if u.Exec != nil {
+			items = append(items, &CheckItem{
+				Kind:    CheckKindExec,
+				Subject: user.Name,
+				Detail:  strings.Join(append([]string{u.Exec.Command}, u.Exec.Args...), " "),
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// EarliestExpiry returns the earliest expiry time in items, nil if no item
+// expires.
+func EarliestExpiry(items []*CheckItem) *time.Time {
+	var earliest *time.Time
+	for _, item := range items {
+		if item.ExpireAt == nil {
+			continue
+		}
+		if earliest == nil || item.ExpireAt.Before(*earliest) {
+			earliest = item.ExpireAt
+		}
+	}
+	return earliest
+}
+
+func checkCert(data []byte, path, dir string) (*time.Time, error) {
+	if len(data) == 0 {
+		var err error
+		data, err = os.ReadFile(resolvePath(path, dir))
+		if err != nil {
+			return nil, fmt.Errorf("read certificate file: %w", err)
+		}
+	}
+
+	// The data can contain a bundle of certificates, use the earliest one
+	var expireAt *time.Time
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		if expireAt == nil || cert.NotAfter.Before(*expireAt) {
+			notAfter := cert.NotAfter
+			expireAt = &notAfter
+		}
+	}
+
+	if expireAt == nil {
+		return nil, errors.New("no certificate found")
+	}
+	return expireAt, nil
+}
+
+func checkToken(token, path, dir string) (*time.Time, error) {
+	if token == "" {
+		data, err := os.ReadFile(resolvePath(path, dir))
+		if err != nil {
+			return nil, fmt.Errorf("read token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	fields := strings.Split(token, ".")
+	if len(fields) != 3 {
+		// Not a JWT, we cannot know its expiry
+		return nil, nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(fields[1], "="))
+	if err != nil {
+		return nil, nil
+	}
+
+	var claims struct {
+		Exp *int64 `json:"exp"`
+	}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil || claims.Exp == nil {
+		return nil, nil
+	}
+
+	expireAt := time.Unix(*claims.Exp, 0)
+	return &expireAt, nil
+}
+
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
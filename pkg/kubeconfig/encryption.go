@@ -0,0 +1,246 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+	"github.com/fioncat/kubewrap/pkg/secret"
+	"golang.org/x/term"
+)
+
+// envPassphrase can be used to provide passphrase without prompt, useful for
+// scripts.
+const envPassphrase = "KUBEWRAP_PASSPHRASE"
+
+const keyCacheFileName = "key_cache"
+
+type keyFile struct {
+	Salt     []byte `json:"salt"`
+	Checksum []byte `json:"checksum"`
+}
+
+type keyCache struct {
+	Key      []byte `json:"key"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+// keyring provides the key to encrypt and decrypt kubeconfig files. The key is
+// derived from the passphrase lazily. If `key_cache_minutes` is set, the key is
+// cached in the runtime dir with the timeout, so that the user doesn't need to
+// input passphrase every time. Note that the cached key is NOT encrypted, the
+// files are only protected by the permission of runtime dir in that period.
+type keyring struct {
+	cfg *config.Encryption
+
+	runtimeDir string
+
+	key []byte
+}
+
+func (k *keyring) encrypt(data []byte) ([]byte, error) {
+	key, err := k.getKey()
+	if err != nil {
+		return nil, err
+	}
+	return secret.Encrypt(key, data)
+}
+
+func (k *keyring) decrypt(data []byte) ([]byte, error) {
+	if !secret.IsEncrypted(data) {
+		// The file was written before encryption was enabled
+		return data, nil
+	}
+	key, err := k.getKey()
+	if err != nil {
+		return nil, err
+	}
+	return secret.Decrypt(key, data)
+}
+
+func (k *keyring) getKey() ([]byte, error) {
+	if k.key != nil {
+		return k.key, nil
+	}
+
+	cacheEnabled := k.cfg.KeyCacheMinutes > 0
+	if cacheEnabled {
+		key, ok := k.readCache()
+		if ok {
+			k.key = key
+			return key, nil
+		}
+	}
+
+	key, err := k.deriveKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheEnabled {
+		err = k.writeCache(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	k.key = key
+	return key, nil
+}
+
+func (k *keyring) deriveKey() ([]byte, error) {
+	data, err := os.ReadFile(k.cfg.KeyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	if os.IsNotExist(err) {
+		return k.initKey()
+	}
+
+	var kf keyFile
+	err = json.Unmarshal(data, &kf)
+	if err != nil {
+		return nil, fmt.Errorf("decode key file: %w", err)
+	}
+
+	passphrase, err := readPassphrase("Enter passphrase for kubeconfig files: ")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := secret.DeriveKey(passphrase, kf.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(secret.Checksum(key), kf.Checksum) {
+		return nil, errors.New("wrong passphrase")
+	}
+
+	return key, nil
+}
+
+func (k *keyring) initKey() ([]byte, error) {
+	passphrase, err := readPassphrase("Create passphrase for kubeconfig files: ")
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	if os.Getenv(envPassphrase) == "" {
+		var confirm string
+		confirm, err = readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if confirm != passphrase {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+
+	salt, err := secret.NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	key, err := secret.DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&keyFile{
+		Salt:     salt,
+		Checksum: secret.Checksum(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = dirs.EnsureCreate(filepath.Dir(k.cfg.KeyPath))
+	if err != nil {
+		return nil, fmt.Errorf("ensure key file dir: %w", err)
+	}
+	err = atomicfile.Write(k.cfg.KeyPath, data, dirs.FilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("write key file: %w", err)
+	}
+
+	return key, nil
+}
+
+func (k *keyring) readCache() ([]byte, bool) {
+	// Never trust the cache in a directory that other users can write
+	if dirs.CheckPrivate(k.runtimeDir) != nil {
+		return nil, false
+	}
+	path := filepath.Join(k.runtimeDir, keyCacheFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var cache keyCache
+	err = json.Unmarshal(data, &cache)
+	if err != nil || len(cache.Key) != secret.KeySize {
+		return nil, false
+	}
+
+	if time.Now().Unix() >= cache.ExpireAt {
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	return cache.Key, true
+}
+
+func (k *keyring) writeCache(key []byte) error {
+	timeout := time.Duration(k.cfg.KeyCacheMinutes) * time.Minute
+	data, err := json.Marshal(&keyCache{
+		Key:      key,
+		ExpireAt: time.Now().Add(timeout).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dirs.EnsurePrivate(k.runtimeDir)
+	if err != nil {
+		return fmt.Errorf("ensure runtime dir: %w", err)
+	}
+	path := filepath.Join(k.runtimeDir, keyCacheFileName)
+	err = atomicfile.Write(path, data, dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("write key cache: %w", err)
+	}
+	return nil
+}
+
+func readPassphrase(prompt string) (string, error) {
+	passphrase := os.Getenv(envPassphrase)
+	if passphrase != "" {
+		return passphrase, nil
+	}
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return "", fmt.Errorf("open tty to read passphrase (you can use env %s instead): %w", envPassphrase, err)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,180 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// MergeItem is a kubeconfig to be merged.
+type MergeItem struct {
+	Name string
+	Data []byte
+
+	// Dir is used to resolve relative file paths in kubeconfig
+	Dir string
+}
+
+// Merge merges kubeconfigs into one file. The contexts, clusters and users are
+// renamed by the kubeconfig name to make them unique. If flatten is true, the
+// external cert files are embedded into data.
+//
+// The returned mapping is from kubeconfig name to its current context name in
+// the merged file.
+func Merge(items []*MergeItem, flatten bool) (*File, map[string]string, error) {
+	merged := &File{
+		APIVersion: "v1",
+		Kind:       "Config",
+	}
+	currentContexts := make(map[string]string, len(items))
+
+	var (
+		contextNames = make(map[string]struct{})
+		clusterNames = make(map[string]struct{})
+		userNames    = make(map[string]struct{})
+	)
+
+	for _, item := range items {
+		file, err := ParseFile(item.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse kubeconfig %q: %w", item.Name, err)
+		}
+
+		clusterMap := make(map[string]string, len(file.Clusters))
+		for _, cluster := range file.Clusters {
+			if cluster.Cluster == nil {
+				continue
+			}
+			err = resolveCluster(cluster.Cluster, item.Dir, flatten)
+			if err != nil {
+				return nil, nil, fmt.Errorf("kubeconfig %q cluster %q: %w", item.Name, cluster.Name, err)
+			}
+			newName := uniqueName(mergeName(item.Name, cluster.Name, len(file.Clusters)), clusterNames)
+			clusterMap[cluster.Name] = newName
+			cluster.Name = newName
+			merged.Clusters = append(merged.Clusters, cluster)
+		}
+
+		userMap := make(map[string]string, len(file.Users))
+		for _, user := range file.Users {
+			if user.User == nil {
+				continue
+			}
+			err = resolveUser(user.User, item.Dir, flatten)
+			if err != nil {
+				return nil, nil, fmt.Errorf("kubeconfig %q user %q: %w", item.Name, user.Name, err)
+			}
+			newName := uniqueName(mergeName(item.Name, user.Name, len(file.Users)), userNames)
+			userMap[user.Name] = newName
+			user.Name = newName
+			merged.Users = append(merged.Users, user)
+		}
+
+		for _, ctx := range file.Contexts {
+			if ctx.Context == nil {
+				continue
+			}
+			newName := uniqueName(mergeName(item.Name, ctx.Name, len(file.Contexts)), contextNames)
+			if name, ok := clusterMap[ctx.Context.Cluster]; ok {
+				ctx.Context.Cluster = name
+			}
+			if name, ok := userMap[ctx.Context.User]; ok {
+				ctx.Context.User = name
+			}
+			if ctx.Name == file.CurrentContext || currentContexts[item.Name] == "" {
+				currentContexts[item.Name] = newName
+			}
+			ctx.Name = newName
+			merged.Contexts = append(merged.Contexts, ctx)
+		}
+
+		// The top-level fields cannot be merged, the first one wins
+		if merged.Preferences == nil {
+			merged.Preferences = file.Preferences
+		}
+		for key, value := range file.Extra {
+			if merged.Extra == nil {
+				merged.Extra = make(map[string]json.RawMessage)
+			}
+			if _, ok := merged.Extra[key]; !ok {
+				merged.Extra[key] = value
+			}
+		}
+	}
+
+	return merged, currentContexts, nil
+}
+
+// mergeName uses the kubeconfig name directly if it only has one item, which is
+// the most common case.
+func mergeName(kcName, name string, count int) string {
+	if count == 1 {
+		return kcName
+	}
+	return fmt.Sprintf("%s/%s", kcName, name)
+}
+
+func uniqueName(name string, used map[string]struct{}) string {
+	newName := name
+	for i := 2; ; i++ {
+		if _, ok := used[newName]; !ok {
+			break
+		}
+		newName = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[newName] = struct{}{}
+	return newName
+}
+
+func resolveCluster(cluster *Cluster, dir string, flatten bool) error {
+	if cluster.CertificateAuthority == "" {
+		return nil
+	}
+	path := resolvePath(cluster.CertificateAuthority, dir)
+	if !flatten {
+		cluster.CertificateAuthority = path
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read certificate authority file: %w", err)
+	}
+	cluster.CertificateAuthorityData = data
+	cluster.CertificateAuthority = ""
+	return nil
+}
+
+func resolveUser(user *User, dir string, flatten bool) error {
+	if user.ClientCertificate != "" {
+		path := resolvePath(user.ClientCertificate, dir)
+		if flatten {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read client certificate file: %w", err)
+			}
+			user.ClientCertificateData = data
+			path = ""
+		}
+		user.ClientCertificate = path
+	}
+
+	if user.ClientKey != "" {
+		path := resolvePath(user.ClientKey, dir)
+		if flatten {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read client key file: %w", err)
+			}
+			user.ClientKeyData = data
+			path = ""
+		}
+		user.ClientKey = path
+	}
+
+	if user.TokenFile != "" {
+		// Token file is not embedded, same as `kubectl config view --flatten`
+		user.TokenFile = resolvePath(user.TokenFile, dir)
+	}
+	return nil
+}
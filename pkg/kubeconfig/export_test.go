@@ -0,0 +1,186 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const exportSource = `apiVersion: v1
+kind: Config
+current-context: dev
+preferences:
+  colors: true
+extensions:
+- name: foo
+  extension:
+    key: value
+clusters:
+- name: dev
+  cluster:
+    server: https://127.0.0.1:6443
+    disable-compression: true
+    certificate-authority-data: Y2EK
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+    namespace: app
+users:
+- name: dev
+  user:
+    token: secret
+    as: admin
+    as-groups:
+    - system:masters
+    as-uid: "1000"
+    as-user-extra:
+      scopes:
+      - view
+`
+
+func TestMergeRoundTrip(t *testing.T) {
+	// The names are the same as the kubeconfig name, so nothing is renamed
+	merged, currentContexts, err := Merge([]*MergeItem{{Name: "dev", Data: []byte(exportSource)}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged.CurrentContext = currentContexts["dev"]
+
+	data, err := merged.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameYAML(t, []byte(exportSource), data)
+}
+
+func TestMergeRename(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := `clusters:
+- name: a
+  cluster:
+    server: https://a
+    certificate-authority: ca.crt
+- name: b
+  cluster:
+    server: https://b
+contexts:
+- name: a
+  context: {cluster: a, user: u}
+- name: b
+  context: {cluster: b, user: u}
+current-context: b
+users:
+- name: u
+  user:
+    as: admin
+`
+
+	tests := []struct {
+		name    string
+		flatten bool
+		expect  string
+	}{
+		{
+			name: "resolve path",
+			expect: `apiVersion: v1
+kind: Config
+current-context: multi/b
+clusters:
+- name: multi/a
+  cluster:
+    server: https://a
+    certificate-authority: ` + filepath.Join(dir, "ca.crt") + `
+- name: multi/b
+  cluster:
+    server: https://b
+contexts:
+- name: multi/a
+  context: {cluster: multi/a, user: multi}
+- name: multi/b
+  context: {cluster: multi/b, user: multi}
+users:
+- name: multi
+  user:
+    as: admin
+`,
+		},
+		{
+			name:    "flatten",
+			flatten: true,
+			expect: `apiVersion: v1
+kind: Config
+current-context: multi/b
+clusters:
+- name: multi/a
+  cluster:
+    server: https://a
+    certificate-authority-data: Y2E=
+- name: multi/b
+  cluster:
+    server: https://b
+contexts:
+- name: multi/a
+  context: {cluster: multi/a, user: multi}
+- name: multi/b
+  context: {cluster: multi/b, user: multi}
+users:
+- name: multi
+  user:
+    as: admin
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, currentContexts, err := Merge([]*MergeItem{{Name: "multi", Data: []byte(source), Dir: dir}}, tt.flatten)
+			if err != nil {
+				t.Fatal(err)
+			}
+			merged.CurrentContext = currentContexts["multi"]
+
+			data, err := merged.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameYAML(t, []byte(tt.expect), data)
+		})
+	}
+}
+
+func assertSameYAML(t *testing.T, expect, actual []byte) {
+	t.Helper()
+
+	var expectValue, actualValue any
+	for _, item := range []struct {
+		data  []byte
+		value *any
+	}{
+		{expect, &expectValue},
+		{actual, &actualValue},
+	} {
+		jsonData, err := yaml.YAMLToJSON(item.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.Unmarshal(jsonData, item.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !reflect.DeepEqual(expectValue, actualValue) {
+		t.Fatalf("unexpected kubeconfig, expect:\n%s\nactual:\n%s", expect, actual)
+	}
+}
@@ -0,0 +1,303 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// File is the content of a kubeconfig file. The fields not defined here are
+// kept in Extra of each struct, so that a file can be re-encoded without loss.
+type File struct {
+	APIVersion     string `json:"apiVersion,omitempty"`
+	Kind           string `json:"kind,omitempty"`
+	CurrentContext string `json:"current-context"`
+
+	Clusters []*NamedCluster `json:"clusters"`
+	Contexts []*NamedContext `json:"contexts"`
+	Users    []*NamedUser    `json:"users"`
+
+	Preferences json.RawMessage `json:"preferences,omitempty"`
+
+	// Extra keeps the fields not known by this struct, such as the new fields
+	// added by Kubernetes, so that they are not lost when re-encoding.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type NamedCluster struct {
+	Name    string   `json:"name"`
+	Cluster *Cluster `json:"cluster"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type Cluster struct {
+	Server                   string          `json:"server"`
+	TLSServerName            string          `json:"tls-server-name,omitempty"`
+	InsecureSkipTLSVerify    bool            `json:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthority     string          `json:"certificate-authority,omitempty"`
+	CertificateAuthorityData []byte          `json:"certificate-authority-data,omitempty"`
+	ProxyURL                 string          `json:"proxy-url,omitempty"`
+	Extensions               json.RawMessage `json:"extensions,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type NamedContext struct {
+	Name    string   `json:"name"`
+	Context *Context `json:"context"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type Context struct {
+	Cluster    string          `json:"cluster"`
+	User       string          `json:"user"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type NamedUser struct {
+	Name string `json:"name"`
+	User *User  `json:"user"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type User struct {
+	ClientCertificate     string          `json:"client-certificate,omitempty"`
+	ClientCertificateData []byte          `json:"client-certificate-data,omitempty"`
+	ClientKey             string          `json:"client-key,omitempty"`
+	ClientKeyData         []byte          `json:"client-key-data,omitempty"`
+	Token                 string          `json:"token,omitempty"`
+	TokenFile             string          `json:"tokenFile,omitempty"`
+	Username              string          `json:"username,omitempty"`
+	Password              string          `json:"password,omitempty"`
+	Exec                  *Exec           `json:"exec,omitempty"`
+	AuthProvider          json.RawMessage `json:"auth-provider,omitempty"`
+	Extensions            json.RawMessage `json:"extensions,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type Exec struct {
+	APIVersion string   `json:"apiVersion,omitempty"`
+	Command    string   `json:"command"`
+	Args       []string `json:"args,omitempty"`
+
+	Env []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"env,omitempty"`
+
+	InstallHint        string `json:"installHint,omitempty"`
+	ProvideClusterInfo bool   `json:"provideClusterInfo,omitempty"`
+	InteractiveMode    string `json:"interactiveMode,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func ParseFile(data []byte) (*File, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig yaml: %w", err)
+	}
+
+	var file File
+	err = json.Unmarshal(jsonData, &file)
+	if err != nil {
+		return nil, fmt.Errorf("decode kubeconfig: %w", err)
+	}
+	return &file, nil
+}
+
+func (f *File) Encode() ([]byte, error) {
+	jsonData, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(jsonData)
+}
+
+func (f *File) GetContext(name string) *Context {
+	for _, ctx := range f.Contexts {
+		if ctx.Name == name {
+			return ctx.Context
+		}
+	}
+	return nil
+}
+
+func (f *File) GetCluster(name string) *Cluster {
+	for _, cluster := range f.Clusters {
+		if cluster.Name == name {
+			return cluster.Cluster
+		}
+	}
+	return nil
+}
+
+func (f *File) GetUser(name string) *User {
+	for _, user := range f.Users {
+		if user.Name == name {
+			return user.User
+		}
+	}
+	return nil
+}
+
+func (f *File) UnmarshalJSON(data []byte) error {
+	type plain File
+	extra, err := decodeExtra(data, (*plain)(f))
+	f.Extra = extra
+	return err
+}
+
+func (f File) MarshalJSON() ([]byte, error) {
+	type plain File
+	return encodeExtra(plain(f), f.Extra)
+}
+
+func (n *NamedCluster) UnmarshalJSON(data []byte) error {
+	type plain NamedCluster
+	extra, err := decodeExtra(data, (*plain)(n))
+	n.Extra = extra
+	return err
+}
+
+func (n NamedCluster) MarshalJSON() ([]byte, error) {
+	type plain NamedCluster
+	return encodeExtra(plain(n), n.Extra)
+}
+
+func (c *Cluster) UnmarshalJSON(data []byte) error {
+	type plain Cluster
+	extra, err := decodeExtra(data, (*plain)(c))
+	c.Extra = extra
+	return err
+}
+
+func (c Cluster) MarshalJSON() ([]byte, error) {
+	type plain Cluster
+	return encodeExtra(plain(c), c.Extra)
+}
+
+func (n *NamedContext) UnmarshalJSON(data []byte) error {
+	type plain NamedContext
+	extra, err := decodeExtra(data, (*plain)(n))
+	n.Extra = extra
+	return err
+}
+
+func (n NamedContext) MarshalJSON() ([]byte, error) {
+	type plain NamedContext
+	return encodeExtra(plain(n), n.Extra)
+}
+
+func (c *Context) UnmarshalJSON(data []byte) error {
+	type plain Context
+	extra, err := decodeExtra(data, (*plain)(c))
+	c.Extra = extra
+	return err
+}
+
+func (c Context) MarshalJSON() ([]byte, error) {
+	type plain Context
+	return encodeExtra(plain(c), c.Extra)
+}
+
+func (n *NamedUser) UnmarshalJSON(data []byte) error {
+	type plain NamedUser
+	extra, err := decodeExtra(data, (*plain)(n))
+	n.Extra = extra
+	return err
+}
+
+func (n NamedUser) MarshalJSON() ([]byte, error) {
+	type plain NamedUser
+	return encodeExtra(plain(n), n.Extra)
+}
+
+func (u *User) UnmarshalJSON(data []byte) error {
+	type plain User
+	extra, err := decodeExtra(data, (*plain)(u))
+	u.Extra = extra
+	return err
+}
+
+func (u User) MarshalJSON() ([]byte, error) {
+	type plain User
+	return encodeExtra(plain(u), u.Extra)
+}
+
+func (e *Exec) UnmarshalJSON(data []byte) error {
+	type plain Exec
+	extra, err := decodeExtra(data, (*plain)(e))
+	e.Extra = extra
+	return err
+}
+
+func (e Exec) MarshalJSON() ([]byte, error) {
+	type plain Exec
+	return encodeExtra(plain(e), e.Extra)
+}
+
+// decodeExtra decodes data into v (a pointer to struct), returns the fields not
+// defined in v.
+func decodeExtra(data []byte, v any) (map[string]json.RawMessage, error) {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range jsonFieldNames(reflect.TypeOf(v).Elem()) {
+		delete(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// encodeExtra encodes v (a struct) with the extra fields.
+func encodeExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+func jsonFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,158 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+	"github.com/fioncat/kubewrap/pkg/kubectl"
+)
+
+const (
+	healthCacheFileName = "health_cache.json"
+	healthCacheTTL      = time.Minute * 5
+
+	probeTimeout     = time.Second * 3
+	probeConcurrency = 16
+)
+
+type Health struct {
+	kubectl.ProbeResult
+
+	CheckedAt int64 `json:"checked_at"`
+}
+
+// Status returns a short text to describe the health.
+func (h *Health) Status() string {
+	switch {
+	case !h.Reachable:
+		return "down"
+	case h.Expired:
+		return "expired"
+	case !h.Authorized:
+		return "auth-failed"
+	default:
+		return "ok"
+	}
+}
+
+// HealthCache stores the probe results in runtime dir for a short time, so
+// that we don't need to probe clusters every time.
+type HealthCache struct {
+	path  string
+	items map[string]*Health
+}
+
+func LoadHealthCache(runtimeDir string) *HealthCache {
+	cache := &HealthCache{
+		path:  filepath.Join(runtimeDir, healthCacheFileName),
+		items: make(map[string]*Health),
+	}
+	if dirs.CheckPrivate(runtimeDir) != nil {
+		return cache
+	}
+	data, err := os.ReadFile(cache.path)
+	if err != nil {
+		return cache
+	}
+	// The cache is not important, ignore broken data
+	_ = json.Unmarshal(data, &cache.items)
+	return cache
+}
+
+// Get returns the health if it was checked recently.
+func (c *HealthCache) Get(name string) (*Health, bool) {
+	health, ok := c.items[name]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(time.Unix(health.CheckedAt, 0)) > healthCacheTTL {
+		return nil, false
+	}
+	return health, true
+}
+
+func (c *HealthCache) Put(name string, health *Health) {
+	c.items[name] = health
+}
+
+func (c *HealthCache) Save() error {
+	data, err := json.Marshal(c.items)
+	if err != nil {
+		return err
+	}
+	err = dirs.EnsurePrivate(filepath.Dir(c.path))
+	if err != nil {
+		return fmt.Errorf("ensure runtime dir: %w", err)
+	}
+	return atomicfile.Write(c.path, data, dirs.FilePerm)
+}
+
+// ProbeAll probes the clusters of kubeconfigs concurrently. The aliases share
+// the result of their targets.
+func ProbeAll(mgr Manager, k kubectl.Kubectl, kcs []*KubeConfig) map[string]*Health {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		probed  = make(map[string]*Health, len(kcs))
+		limit   = make(chan struct{}, probeConcurrency)
+		targets = make(map[string]struct{}, len(kcs))
+	)
+
+	for _, kc := range kcs {
+		name := probeTarget(kc)
+		if _, ok := targets[name]; ok {
+			continue
+		}
+		targets[name] = struct{}{}
+
+		// Decrypting may require passphrase input, so open paths serially
+		path, cleanup, err := mgr.OpenPath(name)
+		if err != nil {
+			mu.Lock()
+			probed[name] = &Health{
+				ProbeResult: kubectl.ProbeResult{Error: err.Error()},
+				CheckedAt:   time.Now().Unix(),
+			}
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(name, path string, cleanup func()) {
+			defer func() {
+				cleanup()
+				<-limit
+				wg.Done()
+			}()
+
+			result := k.Probe(path, probeTimeout)
+			mu.Lock()
+			probed[name] = &Health{
+				ProbeResult: *result,
+				CheckedAt:   time.Now().Unix(),
+			}
+			mu.Unlock()
+		}(name, path, cleanup)
+	}
+	wg.Wait()
+
+	results := make(map[string]*Health, len(kcs))
+	for _, kc := range kcs {
+		results[kc.Name] = probed[probeTarget(kc)]
+	}
+	return results
+}
+
+func probeTarget(kc *KubeConfig) string {
+	if kc.Alias != "" {
+		return kc.Alias
+	}
+	return kc.Name
+}
@@ -11,37 +11,71 @@ const (
 	envName      = "KUBECONFIG_NAME"
 	envPath      = "KUBECONFIG" // this is used by `kubectl`
 	envNamespace = "KUBECONFIG_NAMESPACE"
+	envContext   = "KUBECONFIG_CONTEXT"
 )
 
+// The values in sourceTemplate must be quoted by shellQuote, they can come from
+// the kubeconfig files synced from remote.
 const sourceTemplate = `
-export %s="%s"
-export %s="%s"
-export %s="%s"
-alias k='kubectl%s'
-alias kk='k9s%s'
+export %s=%s
+export %s=%s
+export %s=%s
+export %s=%s
+alias k=%s
+alias kk=%s
 `
 
 const unsetTemplate = `
 export %s=""
 export %s=""
 export %s=""
+export %s=""
 alias k='kubectl'
 alias kk='k9s'
 `
 
 type Manager interface {
+	Read(name string) ([]byte, error)
 	Put(name string, data []byte) (*KubeConfig, error)
 	Delete(name string) error
 	DeleteAll() error
 
+	Rename(oldName, newName string) (*KubeConfig, error)
+	Copy(src, dest string) (*KubeConfig, error)
+
+	// AddAlias and RemoveAlias manage the aliases in state file, the aliases in
+	// config file cannot be modified.
+	AddAlias(alias, target string) error
+	RemoveAlias(alias string) error
+	ListAliases() []*KubeConfig
+
+	// GetMetadata returns the metadata of kubeconfig, it never returns nil.
+	GetMetadata(name string) *Metadata
+	PutMetadata(name string, meta *Metadata) error
+
 	Current() (*KubeConfig, bool)
 	Get(name string) (*KubeConfig, bool)
 	List() []*KubeConfig
+
+	// Activate prepares the kubeconfig to be used by a session. When encryption
+	// is enabled, the kubeconfig is decrypted to a runtime file, and the runtime
+	// file of current session is removed.
+	Activate(kc *KubeConfig) error
+	// Deactivate cleans the runtime file of current session.
+	Deactivate() error
+
+	// OpenPath returns a plain file path of the kubeconfig that can be used by
+	// kubectl, the returned cleanup function should be called after use.
+	OpenPath(name string) (string, func(), error)
 }
 
 type KubeConfig struct {
 	root string
 
+	// runtimePath is the decrypted file used by session, only available when
+	// encryption is enabled.
+	runtimePath string
+
 	Name  string
 	Alias string
 }
@@ -54,15 +88,38 @@ func (c *KubeConfig) Path() string {
 	return filepath.Join(c.root, name)
 }
 
-func (c *KubeConfig) GenerateSource(ns string) string {
-	var nsSet string
+// SourcePath returns the path to be used by kubectl.
+func (c *KubeConfig) SourcePath() string {
+	if c.runtimePath != "" {
+		return c.runtimePath
+	}
+	return c.Path()
+}
+
+// GenerateSource generates the shell script to use the kubeconfig. The context
+// is selected by session only, the kubeconfig file is not modified.
+func (c *KubeConfig) GenerateSource(ns, context string) string {
+	var args string
+	if len(context) > 0 {
+		args = fmt.Sprintf(" --context %s", shellQuote(context))
+	}
 	if len(ns) > 0 {
-		nsSet = fmt.Sprintf(" -n %s", ns)
+		args += fmt.Sprintf(" -n %s", shellQuote(ns))
 	}
-	source := fmt.Sprintf(sourceTemplate, envName, c.Name, envPath, c.Path(), envNamespace, ns, nsSet, nsSet)
+	source := fmt.Sprintf(sourceTemplate,
+		envName, shellQuote(c.Name),
+		envPath, shellQuote(c.SourcePath()),
+		envNamespace, shellQuote(ns),
+		envContext, shellQuote(context),
+		shellQuote("kubectl"+args), shellQuote("k9s"+args))
 	return strings.TrimSpace(source)
 }
 
+// shellQuote quotes s in single quotes, so that the shell never expands it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func (c *KubeConfig) String() string {
 	s := c.Name
 	if c.Alias != "" {
@@ -72,10 +129,22 @@ func (c *KubeConfig) String() string {
 }
 
 func UnsetSource() string {
-	source := fmt.Sprintf(unsetTemplate, envName, envPath, envNamespace)
+	source := fmt.Sprintf(unsetTemplate, envName, envPath, envNamespace, envContext)
 	return strings.TrimSpace(source)
 }
 
+// GetCurrentName returns the name of kubeconfig used by session, without
+// checking whether it exists.
+func GetCurrentName() string {
+	return os.Getenv(envName)
+}
+
 func GetCurrentNamespace() string {
 	return os.Getenv(envNamespace)
 }
+
+// GetCurrentContext returns the context selected by session, empty means using
+// the current-context in kubeconfig file.
+func GetCurrentContext() string {
+	return os.Getenv(envContext)
+}
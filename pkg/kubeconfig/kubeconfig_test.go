@@ -0,0 +1,76 @@
+package kubeconfig
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSourceQuote(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "injected")
+	for _, value := range []string{
+		"plain",
+		`a"; touch ` + marker + `; echo "`,
+		`a'; touch ` + marker + `; echo '`,
+		"$(touch " + marker + ")",
+		"`touch " + marker + "`",
+		"a; touch " + marker,
+	} {
+		kc := &KubeConfig{root: dir, Name: value}
+		source := kc.GenerateSource(value, value)
+
+		script := source + `
+printf '%s\n' "$KUBECONFIG_NAME" "$KUBECONFIG_NAMESPACE" "$KUBECONFIG_CONTEXT"
+alias k
+test ! -e ` + marker
+		output, err := exec.Command(bash, "-c", "shopt -s expand_aliases\n"+script).CombinedOutput()
+		if err != nil {
+			t.Fatalf("value %q: source failed or injected: %v, output: %s", value, err, output)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		if len(lines) < 3 || lines[0] != value || lines[1] != value || lines[2] != value {
+			t.Fatalf("value %q: unexpected env: %q", value, lines)
+		}
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"dev", true},
+		{"team/dev", true},
+		{"a..b", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../dev", false},
+		{"team/../../dev", false},
+		{"/etc/passwd", false},
+		{"team//dev", false},
+		{"team/dev/", false},
+		{"./dev", false},
+		{"sync", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkName(tt.name)
+			if tt.valid && err != nil {
+				t.Fatalf("expect valid, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expect invalid")
+			}
+		})
+	}
+}
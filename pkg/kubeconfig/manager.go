@@ -5,25 +5,50 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
 	"github.com/fioncat/kubewrap/pkg/dirs"
 )
 
+const runtimeFilePrefix = "session-"
+
 type manager struct {
 	root string
 
+	runtimeDir string
+	// keyring is nil when encryption is disabled
+	keyring *keyring
+
+	aliasPath    string
+	stateAliases map[string]string
+
+	metadataPath string
+	metadata     map[string]*Metadata
+
 	current *KubeConfig
 	configs map[string]*KubeConfig
 }
 
-func NewManager(root string, alias map[string]string) (Manager, error) {
+func NewManager(cfg *config.Config) (Manager, error) {
 	mgr := &manager{
-		root:    root,
-		current: nil,
-		configs: make(map[string]*KubeConfig),
+		root:         cfg.KubeConfig.Root,
+		runtimeDir:   cfg.RuntimeDir,
+		aliasPath:    cfg.KubeConfig.AliasPath,
+		metadataPath: cfg.KubeConfig.MetadataPath,
+		current:      nil,
+		configs:      make(map[string]*KubeConfig),
+	}
+	if cfg.KubeConfig.Encryption.Enable {
+		mgr.keyring = &keyring{
+			cfg:        &cfg.KubeConfig.Encryption,
+			runtimeDir: cfg.RuntimeDir,
+		}
 	}
-	err := mgr.init(alias)
+	err := mgr.init(cfg.KubeConfig.Alias)
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +63,10 @@ func (m *manager) init(alias map[string]string) error {
 		if info.IsDir() {
 			return nil
 		}
+		if atomicfile.IsTemp(info.Name()) {
+			// Left by an interrupted atomic write, ignore it
+			return nil
+		}
 
 		name, err := filepath.Rel(m.root, path)
 		if err != nil {
@@ -58,20 +87,36 @@ func (m *manager) init(alias map[string]string) error {
 		return fmt.Errorf("read kubeconfig root: %w", err)
 	}
 
-	for alias, target := range alias {
-		_, ok := m.configs[alias]
-		if ok {
-			return fmt.Errorf("alias %q is already used by a kubeconfig", alias)
-		}
-		_, ok = m.configs[target]
-		if !ok {
-			return fmt.Errorf("alias %q target %q not found", alias, target)
-		}
-		m.configs[alias] = &KubeConfig{
-			root: m.root,
+	m.stateAliases, err = loadAliasState(m.aliasPath)
+	if err != nil {
+		return err
+	}
+
+	m.metadata, err = loadMetadata(m.metadataPath)
+	if err != nil {
+		return err
+	}
+
+	for _, aliases := range []map[string]string{alias, m.stateAliases} {
+		for alias, target := range aliases {
+			_, ok := m.configs[alias]
+			if ok {
+				return fmt.Errorf("alias %q is already used by a kubeconfig", alias)
+			}
+			err = checkName(alias)
+			if err != nil {
+				return err
+			}
+			_, ok = m.configs[target]
+			if !ok {
+				return fmt.Errorf("alias %q target %q not found", alias, target)
+			}
+			m.configs[alias] = &KubeConfig{
+				root: m.root,
 
-			Name:  alias,
-			Alias: target,
+				Name:  alias,
+				Alias: target,
+			}
 		}
 	}
 
@@ -82,14 +127,40 @@ func (m *manager) init(alias map[string]string) error {
 			return fmt.Errorf("current kubeconfig %q not found, please unuse it", currentName)
 		}
 		m.current = currentConfig
+
+		currentPath := os.Getenv(envPath)
+		if m.isRuntimeFile(currentPath) {
+			m.current.runtimePath = currentPath
+		}
 	}
 
 	return nil
 }
 
+func (m *manager) Read(name string) ([]byte, error) {
+	config, ok := m.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig %q not found", name)
+	}
+
+	data, err := os.ReadFile(config.Path())
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig file: %w", err)
+	}
+
+	if m.keyring == nil {
+		return data, nil
+	}
+	return m.keyring.decrypt(data)
+}
+
 func (m *manager) Put(name string, data []byte) (*KubeConfig, error) {
 	config, ok := m.configs[name]
 	if !ok {
+		err := checkName(name)
+		if err != nil {
+			return nil, err
+		}
 		config = &KubeConfig{
 			root: m.root,
 			Name: name,
@@ -102,10 +173,26 @@ func (m *manager) Put(name string, data []byte) (*KubeConfig, error) {
 		return nil, fmt.Errorf("ensure kubeconfig dir: %w", err)
 	}
 
-	err = os.WriteFile(path, data, 0644)
+	fileData := data
+	if m.keyring != nil {
+		fileData, err = m.keyring.encrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt kubeconfig: %w", err)
+		}
+	}
+
+	err = atomicfile.Write(path, fileData, dirs.FilePerm)
 	if err != nil {
 		return nil, fmt.Errorf("write kubeconfig file: %w", err)
 	}
+
+	if config.runtimePath != "" {
+		// Keep the session in sync with the new content
+		err = atomicfile.Write(config.runtimePath, data, dirs.FilePerm)
+		if err != nil {
+			return nil, fmt.Errorf("write runtime kubeconfig file: %w", err)
+		}
+	}
 	return config, nil
 }
 
@@ -115,7 +202,7 @@ func (m *manager) Delete(name string) error {
 	}
 	for _, kubeconfig := range m.configs {
 		if kubeconfig.Alias == name {
-			return errors.New("this kubeconfig is used by an alias, please delete the alias in config file first")
+			return fmt.Errorf("this kubeconfig is used by alias %q, please delete the alias first", kubeconfig.Name)
 		}
 	}
 
@@ -125,6 +212,9 @@ func (m *manager) Delete(name string) error {
 	}
 
 	if config.Alias != "" {
+		if m.isStateAlias(name) {
+			return m.RemoveAlias(name)
+		}
 		return errors.New("cannot delete an alias kubeconfig, please delete it from config file")
 	}
 
@@ -134,13 +224,135 @@ func (m *manager) Delete(name string) error {
 		return fmt.Errorf("delete kubeconfig file: %w", err)
 	}
 
-	var dir string
-	for {
-		dir = filepath.Dir(path)
-		if dir == m.root {
-			break
+	err = m.removeEmptyDirs(path)
+	if err != nil {
+		return err
+	}
+
+	delete(m.configs, name)
+	return m.moveMetadata(name, "")
+}
+
+func (m *manager) Rename(oldName, newName string) (*KubeConfig, error) {
+	if m.current != nil && m.current.Name == oldName {
+		return nil, errors.New("cannot rename current kubeconfig, please unuse it first")
+	}
+	config, ok := m.configs[oldName]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig %q not found", oldName)
+	}
+	if _, ok = m.configs[newName]; ok {
+		return nil, fmt.Errorf("name %q is already used by a kubeconfig or alias", newName)
+	}
+	err := checkName(newName)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Alias != "" {
+		if !m.isStateAlias(oldName) {
+			return nil, errors.New("cannot rename an alias defined in config file, please rename it in config file")
+		}
+		delete(m.stateAliases, oldName)
+		m.stateAliases[newName] = config.Alias
+		err := m.saveAliasState()
+		if err != nil {
+			return nil, err
+		}
+
+		delete(m.configs, oldName)
+		config.Name = newName
+		m.configs[newName] = config
+		return config, nil
+	}
+
+	var targetAliases []*KubeConfig
+	for _, kubeconfig := range m.configs {
+		if kubeconfig.Alias != oldName {
+			continue
+		}
+		if !m.isStateAlias(kubeconfig.Name) {
+			return nil, fmt.Errorf("this kubeconfig is used by alias %q in config file, please update the config file first", kubeconfig.Name)
 		}
+		targetAliases = append(targetAliases, kubeconfig)
+	}
 
+	oldPath := config.Path()
+	newConfig := &KubeConfig{
+		root: m.root,
+		Name: newName,
+	}
+	newPath := newConfig.Path()
+	err = dirs.EnsureCreate(filepath.Dir(newPath))
+	if err != nil {
+		return nil, fmt.Errorf("ensure kubeconfig dir: %w", err)
+	}
+	err = os.Rename(oldPath, newPath)
+	if err != nil {
+		return nil, fmt.Errorf("rename kubeconfig file: %w", err)
+	}
+	err = m.removeEmptyDirs(oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(m.configs, oldName)
+	m.configs[newName] = newConfig
+
+	err = m.moveMetadata(oldName, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetAliases) > 0 {
+		for _, alias := range targetAliases {
+			alias.Alias = newName
+			m.stateAliases[alias.Name] = newName
+		}
+		err = m.saveAliasState()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newConfig, nil
+}
+
+func (m *manager) Copy(src, dest string) (*KubeConfig, error) {
+	if _, ok := m.configs[dest]; ok {
+		return nil, fmt.Errorf("name %q is already used by a kubeconfig or alias", dest)
+	}
+	data, err := m.Read(src)
+	if err != nil {
+		return nil, err
+	}
+	config, err := m.Put(dest, data)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := m.GetMetadata(src)
+	if meta.isEmpty() {
+		return config, nil
+	}
+	tags := make(map[string]string, len(meta.Tags))
+	for key, value := range meta.Tags {
+		tags[key] = value
+	}
+	err = m.PutMetadata(dest, &Metadata{
+		Tags:        tags,
+		Description: meta.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// removeEmptyDirs removes the empty parent directories of path, stops at root.
+func (m *manager) removeEmptyDirs(path string) error {
+	dir := filepath.Dir(path)
+	for dir != m.root && strings.HasPrefix(dir, m.root) {
 		ents, err := os.ReadDir(dir)
 		if err != nil {
 			return err
@@ -153,9 +365,8 @@ func (m *manager) Delete(name string) error {
 		if err != nil {
 			return err
 		}
+		dir = filepath.Dir(dir)
 	}
-
-	delete(m.configs, name)
 	return nil
 }
 
@@ -175,7 +386,105 @@ func (m *manager) DeleteAll() error {
 	}
 
 	m.configs = nil
-	return os.RemoveAll(m.root)
+	err := os.RemoveAll(m.root)
+	if err != nil {
+		return err
+	}
+
+	m.metadata = make(map[string]*Metadata)
+	return m.saveMetadata()
+}
+
+func (m *manager) Activate(kc *KubeConfig) error {
+	if m.keyring == nil {
+		return nil
+	}
+
+	data, err := m.Read(kc.Name)
+	if err != nil {
+		return err
+	}
+
+	err = dirs.EnsurePrivate(m.runtimeDir)
+	if err != nil {
+		return fmt.Errorf("ensure runtime dir: %w", err)
+	}
+
+	file, err := os.CreateTemp(m.runtimeDir, runtimeFilePrefix+"*")
+	if err != nil {
+		return fmt.Errorf("create runtime kubeconfig file: %w", err)
+	}
+	path := file.Name()
+	file.Close()
+
+	err = atomicfile.Write(path, data, dirs.FilePerm)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("write runtime kubeconfig file: %w", err)
+	}
+
+	err = m.Deactivate()
+	if err != nil {
+		return err
+	}
+
+	kc.runtimePath = path
+	return nil
+}
+
+func (m *manager) Deactivate() error {
+	if m.current == nil || m.current.runtimePath == "" {
+		return nil
+	}
+
+	err := os.Remove(m.current.runtimePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove runtime kubeconfig file: %w", err)
+	}
+	m.current.runtimePath = ""
+	return nil
+}
+
+func (m *manager) OpenPath(name string) (string, func(), error) {
+	config, ok := m.configs[name]
+	if !ok {
+		return "", nil, fmt.Errorf("kubeconfig %q not found", name)
+	}
+	if m.keyring == nil {
+		return config.Path(), func() {}, nil
+	}
+
+	data, err := m.Read(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = dirs.EnsurePrivate(m.runtimeDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("ensure runtime dir: %w", err)
+	}
+	file, err := os.CreateTemp(m.runtimeDir, "open-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp kubeconfig file: %w", err)
+	}
+	path := file.Name()
+	cleanup := func() { os.Remove(path) }
+
+	_, err = file.Write(data)
+	file.Close()
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("write temp kubeconfig file: %w", err)
+	}
+
+	return path, cleanup, nil
+}
+
+func (m *manager) isRuntimeFile(path string) bool {
+	if path == "" {
+		return false
+	}
+	return filepath.Dir(path) == m.runtimeDir && strings.HasPrefix(filepath.Base(path), runtimeFilePrefix)
 }
 
 func (m *manager) Current() (*KubeConfig, bool) {
@@ -197,3 +506,22 @@ func (m *manager) List() []*KubeConfig {
 	})
 	return list
 }
+
+// ReservedNames are the subcommands of `config`, the kubeconfigs with these
+// names cannot be selected by `config NAME`.
+var ReservedNames = []string{"doctor", "sync", "export"}
+
+// checkName validates the name for new kubeconfig or alias. The name is a
+// relative path under root, it cannot escape from root.
+func checkName(name string) error {
+	if name == "" || name == "." || filepath.IsAbs(name) || filepath.Clean(name) != name {
+		return fmt.Errorf("invalid name %q, should be a clean relative path", name)
+	}
+	if slices.Contains(strings.Split(name, "/"), "..") {
+		return fmt.Errorf("invalid name %q, cannot contain \"..\"", name)
+	}
+	if slices.Contains(ReservedNames, name) {
+		return fmt.Errorf("name %q is reserved by subcommand `config %s`", name, name)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+)
+
+// Metadata is the extra information attached to a kubeconfig. The metadata of
+// an alias is the metadata of its target.
+type Metadata struct {
+	Tags map[string]string `json:"tags,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// Match reports whether the metadata matches all the tag filters. A filter can
+// be "key=value", or "key" to only require the tag to exist.
+func (m *Metadata) Match(filters []string) bool {
+	for _, filter := range filters {
+		key, value, hasValue := strings.Cut(filter, "=")
+		tagValue, ok := m.Tags[key]
+		if !ok {
+			return false
+		}
+		if hasValue && tagValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatTags returns the tags in "key=value,..." format, sorted by key.
+func (m *Metadata) FormatTags() string {
+	keys := make([]string, 0, len(m.Tags))
+	for key := range m.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := make([]string, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, fmt.Sprintf("%s=%s", key, m.Tags[key]))
+	}
+	return strings.Join(tags, ",")
+}
+
+func (m *Metadata) isEmpty() bool {
+	return len(m.Tags) == 0 && m.Description == ""
+}
+
+// ParseTags parses tags in "key=value" format.
+func ParseTags(tags []string) (map[string]string, error) {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid tag %q, should be 'key=value'", tag)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func loadMetadata(path string) (map[string]*Metadata, error) {
+	metadata := make(map[string]*Metadata)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return metadata, nil
+		}
+		return nil, fmt.Errorf("read metadata file: %w", err)
+	}
+
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("decode metadata file: %w", err)
+	}
+	return metadata, nil
+}
+
+// LoadMetadata reads the metadata of kubeconfig from the state files directly,
+// without scanning the kubeconfig root. It is used where the latency matters,
+// the name is not validated. It never returns nil metadata.
+func LoadMetadata(cfg *config.Config, name string) (*Metadata, error) {
+	metadata, err := loadMetadata(cfg.KubeConfig.MetadataPath)
+	if err != nil {
+		return nil, err
+	}
+	if meta, ok := metadata[name]; ok {
+		return meta, nil
+	}
+
+	target, ok := cfg.KubeConfig.Alias[name]
+	if !ok {
+		stateAliases, err := loadAliasState(cfg.KubeConfig.AliasPath)
+		if err != nil {
+			return nil, err
+		}
+		target = stateAliases[name]
+	}
+	if meta, ok := metadata[target]; ok {
+		return meta, nil
+	}
+	return &Metadata{}, nil
+}
+
+func (m *manager) saveMetadata() error {
+	data, err := json.MarshalIndent(m.metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = dirs.EnsureCreate(filepath.Dir(m.metadataPath))
+	if err != nil {
+		return fmt.Errorf("ensure metadata file dir: %w", err)
+	}
+	err = atomicfile.Write(m.metadataPath, data, dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("write metadata file: %w", err)
+	}
+	return nil
+}
+
+func (m *manager) GetMetadata(name string) *Metadata {
+	config, ok := m.configs[name]
+	if ok && config.Alias != "" {
+		name = config.Alias
+	}
+	meta, ok := m.metadata[name]
+	if !ok {
+		return &Metadata{}
+	}
+	return meta
+}
+
+func (m *manager) PutMetadata(name string, meta *Metadata) error {
+	config, ok := m.configs[name]
+	if !ok {
+		return fmt.Errorf("kubeconfig %q not found", name)
+	}
+	if config.Alias != "" {
+		name = config.Alias
+	}
+
+	if meta.isEmpty() {
+		delete(m.metadata, name)
+	} else {
+		m.metadata[name] = meta
+	}
+	return m.saveMetadata()
+}
+
+// moveMetadata moves the metadata of oldName to newName, if newName is empty,
+// the metadata is removed.
+func (m *manager) moveMetadata(oldName, newName string) error {
+	meta, ok := m.metadata[oldName]
+	if !ok {
+		return nil
+	}
+	delete(m.metadata, oldName)
+	if newName != "" {
+		m.metadata[newName] = meta
+	}
+	return m.saveMetadata()
+}
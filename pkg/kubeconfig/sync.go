@@ -0,0 +1,357 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fioncat/kubewrap/config"
+	"github.com/fioncat/kubewrap/pkg/atomicfile"
+	"github.com/fioncat/kubewrap/pkg/dirs"
+)
+
+// SyncResult reports the changes made by syncing a source.
+type SyncResult struct {
+	Source string
+
+	Added   []string
+	Changed []string
+	Removed []string
+
+	Skipped []*SyncSkip
+}
+
+type SyncSkip struct {
+	Name   string
+	Reason string
+}
+
+// syncState records the kubeconfig names created by each source.
+type syncState map[string][]string
+
+// Sync pulls kubeconfigs from the source into the subtree `<root>/<source>`.
+// Only the kubeconfigs created by previous syncs can be updated or removed,
+// the manually created ones are never touched.
+func Sync(mgr Manager, statePath string, src *config.SyncSource) (*SyncResult, error) {
+	var (
+		configs map[string][]byte
+		skipped []*SyncSkip
+		err     error
+	)
+	if src.Dir != "" {
+		configs, skipped, err = fetchDir(src)
+	} else {
+		configs, skipped, err = fetchCommand(src)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch kubeconfigs from source %q: %w", src.Name, err)
+	}
+
+	state, err := loadSyncState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]struct{}, len(state[src.Name]))
+	for _, name := range state[src.Name] {
+		owned[name] = struct{}{}
+	}
+
+	result := &SyncResult{Source: src.Name, Skipped: skipped}
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var newOwned []string
+	for _, name := range names {
+		data := configs[name]
+		_, isOwned := owned[name]
+		kc, exists := mgr.Get(name)
+		if exists && (!isOwned || kc.Alias != "") {
+			result.Skipped = append(result.Skipped, &SyncSkip{
+				Name:   name,
+				Reason: "conflicts with a manually created kubeconfig",
+			})
+			continue
+		}
+		newOwned = append(newOwned, name)
+
+		if exists {
+			current, err := mgr.Read(name)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(current, data) {
+				continue
+			}
+		}
+
+		_, err = mgr.Put(name, data)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			result.Changed = append(result.Changed, name)
+		} else {
+			result.Added = append(result.Added, name)
+		}
+	}
+
+	invalid := make(map[string]struct{}, len(skipped))
+	for _, skip := range skipped {
+		invalid[skip.Name] = struct{}{}
+	}
+	for _, name := range state[src.Name] {
+		if _, ok := configs[name]; ok {
+			continue
+		}
+		if _, ok := invalid[name]; ok {
+			// The source is broken temporarily, keep the old one
+			newOwned = append(newOwned, name)
+			continue
+		}
+		if _, exists := mgr.Get(name); !exists {
+			continue
+		}
+		err = mgr.Delete(name)
+		if err != nil {
+			// Keep it in state, so that we can remove it next time
+			newOwned = append(newOwned, name)
+			result.Skipped = append(result.Skipped, &SyncSkip{
+				Name:   name,
+				Reason: fmt.Sprintf("remove: %v", err),
+			})
+			continue
+		}
+		result.Removed = append(result.Removed, name)
+	}
+
+	sort.Strings(newOwned)
+	if len(newOwned) == 0 {
+		delete(state, src.Name)
+	} else {
+		state[src.Name] = newOwned
+	}
+	err = saveSyncState(statePath, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func fetchDir(src *config.SyncSource) (map[string][]byte, []*SyncSkip, error) {
+	configs := make(map[string][]byte)
+	var skipped []*SyncSkip
+	err := filepath.Walk(src.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != src.Dir {
+			// Hidden files and dirs, such as ".git", are not kubeconfigs
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src.Dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(src.Name, rel)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, err := ParseFile(data)
+		if err == nil {
+			err = validateSyncFile(file)
+		}
+		if err != nil {
+			skipped = append(skipped, &SyncSkip{Name: name, Reason: err.Error()})
+			return nil
+		}
+
+		// The file is copied into kubeconfig root, the relative paths would be
+		// broken
+		if hasRelativePath(file) {
+			for _, cluster := range file.Clusters {
+				if cluster.Cluster != nil {
+					_ = resolveCluster(cluster.Cluster, filepath.Dir(path), false)
+				}
+			}
+			for _, user := range file.Users {
+				if user.User != nil {
+					_ = resolveUser(user.User, filepath.Dir(path), false)
+				}
+			}
+			data, err = file.Encode()
+			if err != nil {
+				return fmt.Errorf("encode kubeconfig %q: %w", name, err)
+			}
+		}
+
+		configs[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return configs, skipped, nil
+}
+
+func fetchCommand(src *config.SyncSource) (map[string][]byte, []*SyncSkip, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(src.Command[0], src.Command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, nil, fmt.Errorf("run command %q: %w", strings.Join(src.Command, " "), err)
+	}
+
+	configs := make(map[string][]byte)
+	var skipped []*SyncSkip
+	for i, doc := range splitYamlDocuments(stdout.Bytes()) {
+		file, err := ParseFile(doc)
+		if err == nil {
+			err = validateSyncFile(file)
+		}
+		if err != nil {
+			skipped = append(skipped, &SyncSkip{
+				Name:   fmt.Sprintf("%s/<document %d>", src.Name, i),
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		contextName := file.CurrentContext
+		if contextName == "" {
+			contextName = file.Contexts[0].Name
+		}
+		name, err := commandConfigName(src.Name, contextName)
+		if err != nil {
+			skipped = append(skipped, &SyncSkip{
+				Name:   fmt.Sprintf("%s/<document %d>", src.Name, i),
+				Reason: err.Error(),
+			})
+			continue
+		}
+		if _, ok := configs[name]; ok {
+			skipped = append(skipped, &SyncSkip{Name: name, Reason: "duplicate context name in command output"})
+			continue
+		}
+		configs[name] = doc
+	}
+	return configs, skipped, nil
+}
+
+// commandConfigName returns the kubeconfig name of a document in command
+// output, named by its context under the source.
+func commandConfigName(srcName, contextName string) (string, error) {
+	// Context names such as EKS ARNs can contain slashes
+	base := strings.ReplaceAll(contextName, "/", "_")
+	if base == "" || base == "." || base == ".." {
+		// The name would resolve to the source directory or its parent
+		return "", fmt.Errorf("invalid context name %q", contextName)
+	}
+	return filepath.Join(srcName, base), nil
+}
+
+func splitYamlDocuments(data []byte) [][]byte {
+	var (
+		docs    [][]byte
+		current bytes.Buffer
+	)
+	flush := func() {
+		doc := bytes.TrimSpace(current.Bytes())
+		if len(doc) > 0 {
+			docs = append(docs, append(doc, '\n'))
+		}
+		current.Reset()
+	}
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.TrimRight(line, " \r\n") == "---" {
+			flush()
+			continue
+		}
+		current.WriteString(line)
+	}
+	flush()
+	return docs
+}
+
+// hasRelativePath reports whether the file refers to files by relative paths.
+func hasRelativePath(file *File) bool {
+	isRelative := func(path string) bool {
+		return path != "" && !filepath.IsAbs(path)
+	}
+	for _, cluster := range file.Clusters {
+		if cluster.Cluster != nil && isRelative(cluster.Cluster.CertificateAuthority) {
+			return true
+		}
+	}
+	for _, user := range file.Users {
+		if user.User == nil {
+			continue
+		}
+		if isRelative(user.User.ClientCertificate) || isRelative(user.User.ClientKey) || isRelative(user.User.TokenFile) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSyncFile(file *File) error {
+	if len(file.Contexts) == 0 {
+		return errors.New("not a kubeconfig, no context found")
+	}
+	return nil
+}
+
+func loadSyncState(path string) (syncState, error) {
+	state := make(syncState)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("read sync state file: %w", err)
+	}
+
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, fmt.Errorf("decode sync state file: %w", err)
+	}
+	return state, nil
+}
+
+func saveSyncState(path string, state syncState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = dirs.EnsureCreate(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("ensure sync state file dir: %w", err)
+	}
+	err = atomicfile.Write(path, data, dirs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("write sync state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fioncat/kubewrap/config"
+)
+
+func testKubeconfig(server string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context: {cluster: test, user: test}
+users:
+- name: test
+  user:
+    token: secret
+`, server)
+}
+
+// writeFiles writes the files into dir, the empty content means removing the
+// file.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if content == "" {
+			err := os.Remove(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFetchDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		configs []string
+		skipped []string
+	}{
+		{
+			name: "nested",
+			files: map[string]string{
+				"a":          testKubeconfig("https://a"),
+				"team/b":     testKubeconfig("https://b"),
+				".git/HEAD":  "ref: refs/heads/main",
+				".hidden":    testKubeconfig("https://hidden"),
+				"team/c.txt": "not a kubeconfig",
+			},
+			configs: []string{"src/a", "src/team/b"},
+			skipped: []string{"src/team/c.txt"},
+		},
+		{
+			name: "no context",
+			files: map[string]string{
+				"a": "apiVersion: v1\nkind: Config\n",
+			},
+			skipped: []string{"src/a"},
+		},
+		{
+			name:  "empty",
+			files: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			configs, skipped, err := fetchDir(&config.SyncSource{Name: "src", Dir: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := make([]string, 0, len(configs))
+			for name := range configs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			assertNames(t, "configs", tt.configs, names)
+			assertNames(t, "skipped", tt.skipped, skipNames(skipped))
+		})
+	}
+}
+
+func TestFetchDirResolvePaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"team/a": `current-context: test
+clusters:
+- name: test
+  cluster:
+    server: https://a
+    certificate-authority: certs/ca.crt
+contexts:
+- name: test
+  context: {cluster: test, user: test}
+users:
+- name: test
+  user:
+    client-certificate: certs/client.crt
+    client-key: /abs/client.key
+`,
+	})
+
+	configs, _, err := fetchDir(&config.SyncSource{Name: "src", Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := ParseFile(configs["src/team/a"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certsDir := filepath.Join(dir, "team", "certs")
+	if got := file.GetCluster("test").CertificateAuthority; got != filepath.Join(certsDir, "ca.crt") {
+		t.Fatalf("unexpected certificate authority %q", got)
+	}
+	user := file.GetUser("test")
+	if user.ClientCertificate != filepath.Join(certsDir, "client.crt") {
+		t.Fatalf("unexpected client certificate %q", user.ClientCertificate)
+	}
+	if user.ClientKey != "/abs/client.key" {
+		t.Fatalf("unexpected client key %q", user.ClientKey)
+	}
+}
+
+func TestFetchCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		context string
+		configs []string
+		skipped []string
+	}{
+		{
+			name:    "plain",
+			context: "dev",
+			configs: []string{"src/dev"},
+		},
+		{
+			name:    "slash",
+			context: "arn:aws:eks:us-east-1:1:cluster/prod",
+			configs: []string{"src/arn:aws:eks:us-east-1:1:cluster_prod"},
+		},
+		{
+			name:    "dot",
+			context: ".",
+			skipped: []string{"src/<document 0>"},
+		},
+		{
+			name:    "dot dot",
+			context: "..",
+			skipped: []string{"src/<document 0>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			data := strings.ReplaceAll(testKubeconfig("https://a"), "test", tt.context)
+			writeFiles(t, dir, map[string]string{"out": data})
+
+			configs, skipped, err := fetchCommand(&config.SyncSource{
+				Name:    "src",
+				Command: []string{"cat", filepath.Join(dir, "out")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := make([]string, 0, len(configs))
+			for name := range configs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			assertNames(t, "configs", tt.configs, names)
+			assertNames(t, "skipped", tt.skipped, skipNames(skipped))
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	root := t.TempDir()
+	srcDir := t.TempDir()
+	statePath := filepath.Join(root, ".sync_state")
+
+	cfg := &config.Config{
+		RuntimeDir: filepath.Join(root, ".runtime"),
+		KubeConfig: config.KubeConfig{
+			Root:         filepath.Join(root, "configs"),
+			AliasPath:    filepath.Join(root, ".alias"),
+			MetadataPath: filepath.Join(root, ".metadata"),
+		},
+	}
+	src := &config.SyncSource{Name: "src", Dir: srcDir}
+
+	// The steps run in order, each one is based on the previous
+	steps := []struct {
+		name string
+
+		files  map[string]string
+		manual map[string]string
+
+		added   []string
+		changed []string
+		removed []string
+		skipped []string
+	}{
+		{
+			name: "add",
+			files: map[string]string{
+				"a":     testKubeconfig("https://a"),
+				"dev/b": testKubeconfig("https://b"),
+			},
+			added: []string{"src/a", "src/dev/b"},
+		},
+		{
+			name: "no change",
+		},
+		{
+			name: "change and remove",
+			files: map[string]string{
+				"a":     testKubeconfig("https://a2"),
+				"dev/b": "",
+			},
+			changed: []string{"src/a"},
+			removed: []string{"src/dev/b"},
+		},
+		{
+			name: "conflict with manual",
+			files: map[string]string{
+				"c": testKubeconfig("https://c"),
+			},
+			manual: map[string]string{
+				"src/c": testKubeconfig("https://manual"),
+			},
+			skipped: []string{"src/c"},
+		},
+		{
+			name: "keep owned when invalid",
+			files: map[string]string{
+				"a": "broken",
+			},
+			skipped: []string{"src/a", "src/c"},
+		},
+		{
+			name: "remove owned when deleted",
+			files: map[string]string{
+				"a": "",
+			},
+			removed: []string{"src/a"},
+			skipped: []string{"src/c"},
+		},
+	}
+
+	for _, step := range steps {
+		writeFiles(t, srcDir, step.files)
+
+		mgr, err := NewManager(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		for name, content := range step.manual {
+			_, err = mgr.Put(name, []byte(content))
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+
+		result, err := Sync(mgr, statePath, src)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		assertNames(t, step.name+" added", step.added, result.Added)
+		assertNames(t, step.name+" changed", step.changed, result.Changed)
+		assertNames(t, step.name+" removed", step.removed, result.Removed)
+		assertNames(t, step.name+" skipped", step.skipped, skipNames(result.Skipped))
+	}
+
+	// The manual kubeconfig is never touched
+	mgr, err := NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mgr.Read("src/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testKubeconfig("https://manual") {
+		t.Fatalf("manual kubeconfig is modified: %s", data)
+	}
+	if _, ok := mgr.Get("src/a"); ok {
+		t.Fatal("kubeconfig src/a should be removed")
+	}
+}
+
+func skipNames(skipped []*SyncSkip) []string {
+	names := make([]string, 0, len(skipped))
+	for _, skip := range skipped {
+		names = append(names, skip.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func assertNames(t *testing.T, what string, expect, actual []string) {
+	t.Helper()
+	if len(expect) == 0 && len(actual) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(expect, actual) {
+		t.Fatalf("unexpected %s, expect %v, actual %v", what, expect, actual)
+	}
+}
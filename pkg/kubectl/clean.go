@@ -0,0 +1,44 @@
+package kubectl
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The metadata fields managed by server, they are removed by `CleanResource`.
+var serverManagedFields = []string{
+	"managedFields",
+	"resourceVersion",
+	"uid",
+	"creationTimestamp",
+	"generation",
+	"selfLink",
+}
+
+const annotationLastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+
+// CleanResource removes the server-managed fields (such as status, managedFields
+// and uid) from a resource in json format, so that the result can be used as a
+// manifest directly.
+func CleanResource(data []byte) ([]byte, error) {
+	var obj map[string]any
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("decode resource: %w", err)
+	}
+
+	delete(obj, "status")
+	if meta, ok := obj["metadata"].(map[string]any); ok {
+		for _, field := range serverManagedFields {
+			delete(meta, field)
+		}
+		if annotations, ok := meta["annotations"].(map[string]any); ok {
+			delete(annotations, annotationLastApplied)
+			if len(annotations) == 0 {
+				delete(meta, "annotations")
+			}
+		}
+	}
+
+	return json.Marshal(obj)
+}
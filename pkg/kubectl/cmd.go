@@ -2,11 +2,13 @@ package kubectl
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -58,19 +60,31 @@ func (k *cmdKubectl) ListNodes() ([]*Node, error) {
 
 func (k *cmdKubectl) CheckNamespace(name string) error {
 	namespaces, err := k.ListNamespaces()
-	if err != nil {
+	if err == nil {
+		for _, ns := range namespaces {
+			if ns == name {
+				return nil
+			}
+		}
+		return newNotFoundError("namespace", name)
+	}
+	if !IsForbidden(err) {
 		return err
 	}
-	for _, ns := range namespaces {
-		if ns == name {
-			return nil
+
+	// The user may be allowed to get the namespace without list permission
+	_, err = k.query(nil, "get", "namespace", name, "-o", "name")
+	if err != nil {
+		if strings.Contains(err.Error(), serverNotFoundPrefix) {
+			return newNotFoundError("namespace", name)
 		}
+		return err
 	}
-	return newNotFoundError("namespace", name)
+	return nil
 }
 
 func (k *cmdKubectl) ListNamespaces() ([]string, error) {
-	output, err := k.output(nil, "get", "namespaces", "-o", "jsonpath={.items[*].metadata.name}")
+	output, err := k.query(nil, "get", "namespaces", "-o", "jsonpath={.items[*].metadata.name}")
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +100,29 @@ func (k *cmdKubectl) Apply(data []byte) error {
 	return nil
 }
 
+func (k *cmdKubectl) Replace(data []byte) error {
+	args := []string{"replace", "-f", "-"}
+	if len(k.args) > 0 {
+		args = append(k.args, args...)
+	}
+
+	var errBuf bytes.Buffer
+	cmd := exec.Command(k.name, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &errBuf
+
+	err := cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(errBuf.String())
+		if strings.Contains(message, "the object has been modified") {
+			return &ConflictError{message: message}
+		}
+		return fmt.Errorf("kubectl replace failed: %s", message)
+	}
+	return nil
+}
+
 func (k *cmdKubectl) DeletePod(namespace, name string) error {
 	_, err := k.output(nil, "delete", "-n", namespace, "pod", name)
 	return err
@@ -144,28 +181,88 @@ func (k *cmdKubectl) ListContainers(r *Resource) ([]*Container, error) {
 	args := []string{
 		"get", "-n", r.Namespace,
 		r.Type, r.Name,
-		"-o", "jsonpath={.spec.template.spec.containers[*].name}",
+		"-o", `jsonpath={range .spec.template.spec.containers[*]}{.name}{" "}{.image}{"\n"}{end}`,
 	}
-	output, err := k.output(nil, args...)
+	lines, err := k.lines(args...)
 	if err != nil {
 		return nil, err
 	}
-	names := strings.Fields(output)
-	cs := make([]*Container, 0, len(names))
-	for _, name := range names {
-		name = strings.TrimSpace(name)
-		if name == "" {
+	cs := make([]*Container, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
 			continue
 		}
+		var image string
+		if len(fields) > 1 {
+			image = fields[1]
+		}
 		cs = append(cs, &Container{
 			Resource: *r,
 
-			ContainerName: name,
+			ContainerName: fields[0],
+			Image:         image,
 		})
 	}
 	return cs, nil
 }
 
+func (k *cmdKubectl) GetResource(r *Resource) ([]byte, error) {
+	args := []string{
+		"get", "-n", r.Namespace,
+		r.Type, r.Name,
+		"-o", "json",
+	}
+	output, err := k.output(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(output), nil
+}
+
+func (k *cmdKubectl) PrintResource(r *Resource, output string) error {
+	args := []string{"get", "-n", r.Namespace, r.Type, r.Name}
+	if output != "" {
+		args = append(args, "-o", output)
+	}
+	return k.exec(args, true, nil, nil)
+}
+
+func (k *cmdKubectl) Describe(r *Resource) error {
+	args := []string{"describe", "-n", r.Namespace, r.Type, r.Name}
+	return k.exec(args, true, nil, nil)
+}
+
+func (k *cmdKubectl) GetAnnotation(r *Resource, key string) (string, error) {
+	path := strings.ReplaceAll(key, ".", `\.`)
+	args := []string{
+		"get", "-n", r.Namespace,
+		r.Type, r.Name,
+		"-o", fmt.Sprintf("jsonpath={.metadata.annotations.%s}", path),
+	}
+	return k.output(nil, args...)
+}
+
+func (k *cmdKubectl) Annotate(r *Resource, key, value string) error {
+	args := []string{
+		"annotate", "--overwrite", "-n", r.Namespace,
+		fmt.Sprintf("%s/%s", r.Type, r.Name),
+		fmt.Sprintf("%s=%s", key, value),
+	}
+	_, err := k.output(nil, args...)
+	return err
+}
+
+func (k *cmdKubectl) RemoveAnnotation(r *Resource, key string) error {
+	args := []string{
+		"annotate", "-n", r.Namespace,
+		fmt.Sprintf("%s/%s", r.Type, r.Name),
+		key + "-",
+	}
+	_, err := k.output(nil, args...)
+	return err
+}
+
 func (k *cmdKubectl) SetImage(c *Container, image string) error {
 	args := []string{
 		"set", "image", "-n", c.Namespace,
@@ -176,6 +273,77 @@ func (k *cmdKubectl) SetImage(c *Container, image string) error {
 	return err
 }
 
+func (k *cmdKubectl) GetReplicas(r *Resource) (int, error) {
+	args := []string{
+		"get", "-n", r.Namespace,
+		r.Type, r.Name,
+		"-o", "jsonpath={.spec.replicas}",
+	}
+	output, err := k.output(nil, args...)
+	if err != nil {
+		return 0, err
+	}
+	if output == "" {
+		return 0, fmt.Errorf("%v has no replicas field", r)
+	}
+	replicas, err := strconv.Atoi(output)
+	if err != nil {
+		return 0, fmt.Errorf("parse replicas of %v: %w", r, err)
+	}
+	return replicas, nil
+}
+
+func (k *cmdKubectl) ListWorkloads(resourceTypes []string, namespace string) ([]*Workload, error) {
+	output, err := k.output(nil, "get", "-n", namespace, strings.Join(resourceTypes, ","), "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []struct {
+			Kind     string     `json:"kind"`
+			Metadata objectMeta `json:"metadata"`
+			Spec     struct {
+				Replicas *int `json:"replicas"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	err = json.Unmarshal([]byte(output), &list)
+	if err != nil {
+		return nil, fmt.Errorf("decode workloads: %w", err)
+	}
+
+	workloads := make([]*Workload, 0, len(list.Items))
+	for _, item := range list.Items {
+		// Keep the type used by caller, such as "deploy" for Deployment
+		var resourceType string
+		for _, t := range resourceTypes {
+			if ResourceKind(t) == item.Kind {
+				resourceType = t
+				break
+			}
+		}
+		if resourceType == "" {
+			resourceType = strings.ToLower(item.Kind)
+		}
+
+		r := Resource{
+			Type:      resourceType,
+			Namespace: namespace,
+			Name:      item.Metadata.Name,
+		}
+		if item.Spec.Replicas == nil {
+			return nil, fmt.Errorf("%v has no replicas field", &r)
+		}
+		workloads = append(workloads, &Workload{
+			Resource:    r,
+			Replicas:    *item.Spec.Replicas,
+			Annotations: item.Metadata.Annotations,
+		})
+	}
+	return workloads, nil
+}
+
 func (k *cmdKubectl) Scale(r *Resource, replicas int) error {
 	args := []string{
 		"scale", "-n", r.Namespace,
@@ -186,6 +354,38 @@ func (k *cmdKubectl) Scale(r *Resource, replicas int) error {
 	return err
 }
 
+func (k *cmdKubectl) FindHPA(r *Resource) (string, error) {
+	output, err := k.output(nil, "get", "-n", r.Namespace, "hpa", "-o", "json")
+	if err != nil {
+		return "", err
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata objectMeta `json:"metadata"`
+			Spec     struct {
+				ScaleTargetRef struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"scaleTargetRef"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	err = json.Unmarshal([]byte(output), &list)
+	if err != nil {
+		return "", fmt.Errorf("decode hpa: %w", err)
+	}
+
+	kind := ResourceKind(r.Type)
+	for _, item := range list.Items {
+		target := item.Spec.ScaleTargetRef
+		if target.Kind == kind && target.Name == r.Name {
+			return item.Metadata.Name, nil
+		}
+	}
+	return "", nil
+}
+
 func (k *cmdKubectl) RolloutRestart(r *Resource) error {
 	args := []string{
 		"rollout", "restart", "-n", r.Namespace,
@@ -195,6 +395,16 @@ func (k *cmdKubectl) RolloutRestart(r *Resource) error {
 	return err
 }
 
+func (k *cmdKubectl) RolloutUndo(r *Resource, revision int64) error {
+	args := []string{
+		"rollout", "undo", "-n", r.Namespace,
+		fmt.Sprintf("%s/%s", r.Type, r.Name),
+		fmt.Sprintf("--to-revision=%d", revision),
+	}
+	_, err := k.output(nil, args...)
+	return err
+}
+
 func (k *cmdKubectl) lines(args ...string) ([]string, error) {
 	output, err := k.output(nil, args...)
 	if err != nil {
@@ -212,6 +422,42 @@ func (k *cmdKubectl) lines(args ...string) ([]string, error) {
 	return lines, nil
 }
 
+// The prefixes of kubectl error messages for the API status reasons, such as
+// `Error from server (Forbidden): namespaces is forbidden: ...`. Matching the
+// whole prefix avoids misjudging the messages that only mention the words.
+const (
+	serverForbiddenPrefix = "Error from server (Forbidden)"
+	serverNotFoundPrefix  = "Error from server (NotFound)"
+)
+
+// query is like output, but captures stderr to recognize the errors, such as
+// Forbidden.
+func (k *cmdKubectl) query(in io.Reader, args ...string) (string, error) {
+	if len(k.args) > 0 {
+		args = append(append([]string{}, k.args...), args...)
+	}
+
+	var outBuf, errBuf bytes.Buffer
+	cmd := exec.Command(k.name, args...)
+	cmd.Stdin = in
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+
+	err := cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(errBuf.String())
+		// There may be warnings before the error message
+		if strings.Contains(message, serverForbiddenPrefix) {
+			return "", &ForbiddenError{message: message}
+		}
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("kubectl command failed: %s", message)
+	}
+	return strings.TrimSpace(outBuf.String()), nil
+}
+
 func (k *cmdKubectl) output(in io.Reader, args ...string) (string, error) {
 	buf := bytes.NewBuffer(nil)
 	err := k.exec(args, false, in, buf)
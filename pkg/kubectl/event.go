@@ -0,0 +1,164 @@
+package kubectl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type eventList struct {
+	Items []struct {
+		Metadata struct {
+			UID               string    `json:"uid"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+
+		Type    string `json:"type"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+		Count   int    `json:"count"`
+
+		InvolvedObject struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"involvedObject"`
+
+		LastTimestamp  *time.Time `json:"lastTimestamp"`
+		FirstTimestamp *time.Time `json:"firstTimestamp"`
+		EventTime      *time.Time `json:"eventTime"`
+	} `json:"items"`
+}
+
+func (k *cmdKubectl) ListEvents(namespace string) ([]*Event, error) {
+	output, err := k.output(nil, "get", "-n", namespace, "events", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list eventList
+	err = json.Unmarshal([]byte(output), &list)
+	if err != nil {
+		return nil, fmt.Errorf("decode events: %w", err)
+	}
+
+	events := make([]*Event, 0, len(list.Items))
+	for _, item := range list.Items {
+		// The lastTimestamp can be empty for events created by new events
+		// api, fallback to other time fields.
+		lastTime := item.Metadata.CreationTimestamp
+		for _, t := range []*time.Time{item.LastTimestamp, item.EventTime, item.FirstTimestamp} {
+			if t != nil && !t.IsZero() {
+				lastTime = *t
+				break
+			}
+		}
+
+		count := item.Count
+		if count == 0 {
+			count = 1
+		}
+
+		events = append(events, &Event{
+			UID:        item.Metadata.UID,
+			Type:       item.Type,
+			Reason:     item.Reason,
+			Message:    item.Message,
+			Count:      count,
+			ObjectKind: item.InvolvedObject.Kind,
+			ObjectName: item.InvolvedObject.Name,
+			LastTime:   lastTime,
+		})
+	}
+	return events, nil
+}
+
+// ownerSelector is the fields of owner to select the owned resources, the
+// CronJob has no selector, the labels of job template are used instead.
+type ownerSelector struct {
+	Spec struct {
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+
+		JobTemplate struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"jobTemplate"`
+	} `json:"spec"`
+}
+
+func (k *cmdKubectl) ListOwnedResources(r *Resource, resourceType string) ([]*Resource, error) {
+	selector, err := k.getOwnerSelector(r)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"get", "-n", r.Namespace, resourceType, "-o", "json"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	output, err := k.output(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata objectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	err = json.Unmarshal([]byte(output), &list)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", resourceType, err)
+	}
+
+	// The selector may match the resources of other owners, the owner
+	// references are still checked
+	kind := ResourceKind(r.Type)
+	rs := make([]*Resource, 0)
+	for _, item := range list.Items {
+		if !item.Metadata.ownedBy(kind, r.Name) {
+			continue
+		}
+		rs = append(rs, &Resource{
+			Type:      resourceType,
+			Namespace: r.Namespace,
+			Name:      item.Metadata.Name,
+		})
+	}
+	return rs, nil
+}
+
+// getOwnerSelector returns the label selector of the resources owned by r,
+// empty if r has no labels to select.
+func (k *cmdKubectl) getOwnerSelector(r *Resource) (string, error) {
+	output, err := k.output(nil, "get", "-n", r.Namespace, r.Type, r.Name, "-o", "json")
+	if err != nil {
+		return "", err
+	}
+
+	var owner ownerSelector
+	err = json.Unmarshal([]byte(output), &owner)
+	if err != nil {
+		return "", fmt.Errorf("decode %v: %w", r, err)
+	}
+
+	labels := owner.Spec.Selector.MatchLabels
+	if len(labels) == 0 {
+		labels = owner.Spec.JobTemplate.Metadata.Labels
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		terms = append(terms, fmt.Sprintf("%s=%s", key, labels[key]))
+	}
+	return strings.Join(terms, ","), nil
+}
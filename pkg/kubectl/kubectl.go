@@ -1,6 +1,10 @@
 package kubectl
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 type Kubectl interface {
 	CheckNode(name string) error
@@ -8,8 +12,13 @@ type Kubectl interface {
 
 	CheckNamespace(name string) error
 	ListNamespaces() ([]string, error)
+	// ListPermittedNamespaces returns the namespaces that the rules in
+	// namespace allow the user to access by name, without the permission to
+	// list namespaces.
+	ListPermittedNamespaces(namespace string) ([]string, error)
 
 	Apply(data []byte) error
+	Replace(data []byte) error
 	DeletePod(namespace, name string) error
 
 	GetPodStatus(namespace, name string) (string, error)
@@ -19,12 +28,42 @@ type Kubectl interface {
 
 	ListResources(resourceType, namespace string) ([]*Resource, error)
 	ListContainers(r *Resource) ([]*Container, error)
+	GetResource(r *Resource) ([]byte, error)
+	PrintResource(r *Resource, output string) error
+	Describe(r *Resource) error
+
+	GetAnnotation(r *Resource, key string) (string, error)
+	Annotate(r *Resource, key, value string) error
+	RemoveAnnotation(r *Resource, key string) error
 
 	SetImage(c *Container, image string) error
+	GetReplicas(r *Resource) (int, error)
+	// ListWorkloads lists the resources of types in namespace with their
+	// replicas and annotations in one request.
+	ListWorkloads(resourceTypes []string, namespace string) ([]*Workload, error)
 	Scale(r *Resource, replicas int) error
+	FindHPA(r *Resource) (string, error)
 	RolloutRestart(r *Resource) error
+
+	ListOwnedResources(r *Resource, resourceType string) ([]*Resource, error)
+	ListEvents(namespace string) ([]*Event, error)
+
+	ListRevisions(r *Resource) ([]*Revision, error)
+	RolloutUndo(r *Resource, revision int64) error
+
+	// Probe checks the cluster in the kubeconfig file, it never prints to
+	// stderr, the errors are stored in result.
+	Probe(kubeconfigPath string, timeout time.Duration) *ProbeResult
 }
 
+// AnnotationPreviousReplicas records the replicas of a workload before kubewrap
+// scaled it, so that it can be restored later.
+const AnnotationPreviousReplicas = "kubewrap.io/previous-replicas"
+
+// AnnotationParkedReplicas records the replicas of a workload before it was
+// parked (scaled to 0 with other workloads in the namespace).
+const AnnotationParkedReplicas = "kubewrap.io/parked-replicas"
+
 type Node struct {
 	Name        string
 	Description string
@@ -40,15 +79,60 @@ func (r *Resource) String() string {
 	return fmt.Sprintf("%s %s/%s", r.Type, r.Namespace, r.Name)
 }
 
+// Workload is a resource with replicas, such as deployment and statefulset.
+type Workload struct {
+	Resource
+	Replicas    int
+	Annotations map[string]string
+}
+
 type Container struct {
 	Resource
 	ContainerName string
+	Image         string
 }
 
 func (c *Container) String() string {
 	return fmt.Sprintf("%s %s/%s/%s", c.Type, c.Namespace, c.Name, c.ContainerName)
 }
 
+type Revision struct {
+	Number int64
+	Name   string
+
+	Images      []string
+	ChangeCause string
+	CreateTime  time.Time
+
+	// Template is the pod template of this revision, in json format.
+	Template []byte
+}
+
+type Event struct {
+	UID string
+
+	Type    string
+	Reason  string
+	Message string
+	Count   int
+
+	ObjectKind string
+	ObjectName string
+
+	LastTime time.Time
+}
+
+// ProbeResult is the result of probing a cluster. Expired means the credentials
+// are rejected by the cluster (401), and not Authorized without Expired means
+// the user has no permission (403).
+type ProbeResult struct {
+	Reachable  bool   `json:"reachable"`
+	Authorized bool   `json:"authorized"`
+	Expired    bool   `json:"expired,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
 type NotFoundError struct {
 	resourceType string
 	name         string
@@ -63,8 +147,36 @@ func (e *NotFoundError) Error() string {
 }
 
 func IsNotFound(err error) bool {
-	if _, ok := err.(*NotFoundError); ok {
-		return true
-	}
-	return false
+	var notFoundErr *NotFoundError
+	return errors.As(err, &notFoundErr)
+}
+
+// ForbiddenError means the user has no permission, some operations can use
+// other ways to work around it.
+type ForbiddenError struct {
+	message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("forbidden: %s", e.message)
+}
+
+// IsForbidden reports whether any error in err's chain is a ForbiddenError,
+// the callers may wrap it.
+func IsForbidden(err error) bool {
+	var forbiddenErr *ForbiddenError
+	return errors.As(err, &forbiddenErr)
+}
+
+type ConflictError struct {
+	message string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict: %s", e.message)
+}
+
+func IsConflict(err error) bool {
+	var conflictErr *ConflictError
+	return errors.As(err, &conflictErr)
 }
@@ -0,0 +1,92 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+func (k *cmdKubectl) Probe(kubeconfigPath string, timeout time.Duration) *ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := &ProbeResult{}
+
+	// The version api is allowed for anonymous users in most clusters, so
+	// it is used to check reachability only.
+	output, errOutput, err := k.probe(ctx, kubeconfigPath, timeout, "get", "--raw", "/version")
+	switch {
+	case err == nil:
+		result.Reachable = true
+		var version struct {
+			GitVersion string `json:"gitVersion"`
+		}
+		if json.Unmarshal([]byte(output), &version) == nil {
+			result.Version = version.GitVersion
+		}
+
+	case isAuthError(errOutput):
+		result.Reachable = true
+
+	default:
+		result.Error = probeError(errOutput, err)
+		return result
+	}
+
+	_, errOutput, err = k.probe(ctx, kubeconfigPath, timeout, "get", "--raw", "/api")
+	switch {
+	case err == nil:
+		result.Authorized = true
+
+	case strings.Contains(errOutput, serverUnauthorized):
+		// The credentials are rejected, such as expired token or certificate
+		result.Expired = true
+		result.Error = probeError(errOutput, err)
+
+	default:
+		// Including Forbidden, the api discovery is allowed for every
+		// authenticated user, so it means the user is anonymous or the
+		// credentials are not accepted
+		result.Error = probeError(errOutput, err)
+	}
+
+	return result
+}
+
+func (k *cmdKubectl) probe(ctx context.Context, kubeconfigPath string, timeout time.Duration, args ...string) (string, string, error) {
+	args = append([]string{
+		"--kubeconfig", kubeconfigPath,
+		fmt.Sprintf("--request-timeout=%s", timeout),
+	}, args...)
+	if len(k.args) > 0 {
+		args = append(append([]string{}, k.args...), args...)
+	}
+
+	var outBuf, errBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, k.name, args...)
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+
+	err := cmd.Run()
+	return outBuf.String(), errBuf.String(), err
+}
+
+// serverUnauthorized is the reason in kubectl error message for 401.
+const serverUnauthorized = "(Unauthorized)"
+
+func isAuthError(errOutput string) bool {
+	return strings.Contains(errOutput, serverUnauthorized) || strings.Contains(errOutput, serverForbiddenPrefix)
+}
+
+func probeError(errOutput string, err error) string {
+	lines := strings.Split(strings.TrimSpace(errOutput), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last != "" {
+		return last
+	}
+	return err.Error()
+}
@@ -0,0 +1,232 @@
+package kubectl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	annotationRevision    = "deployment.kubernetes.io/revision"
+	annotationChangeCause = "kubernetes.io/change-cause"
+
+	labelPodTemplateHash = "pod-template-hash"
+)
+
+type objectMeta struct {
+	Name              string            `json:"name"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+	Annotations       map[string]string `json:"annotations"`
+	OwnerReferences   []struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"ownerReferences"`
+}
+
+func (m *objectMeta) ownedBy(kind, name string) bool {
+	for _, owner := range m.OwnerReferences {
+		if owner.Kind == kind && owner.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type replicaSetList struct {
+	Items []struct {
+		Metadata objectMeta `json:"metadata"`
+		Spec     struct {
+			Template json.RawMessage `json:"template"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type controllerRevisionList struct {
+	Items []struct {
+		Metadata objectMeta `json:"metadata"`
+		Revision int64      `json:"revision"`
+		Data     struct {
+			Spec struct {
+				Template json.RawMessage `json:"template"`
+			} `json:"spec"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// ResourceKind converts a resource type used in command line (such as "deploy",
+// "sts") to its kind name. Return empty string if the type is not a workload
+// that kubewrap knows.
+func ResourceKind(resourceType string) string {
+	resourceType = strings.TrimSuffix(strings.ToLower(resourceType), ".apps")
+	switch resourceType {
+	case "deploy", "deployment", "deployments":
+		return "Deployment"
+	case "sts", "statefulset", "statefulsets":
+		return "StatefulSet"
+	case "ds", "daemonset", "daemonsets":
+		return "DaemonSet"
+	case "rs", "replicaset", "replicasets":
+		return "ReplicaSet"
+	case "job", "jobs":
+		return "Job"
+	case "cronjob", "cronjobs", "cj":
+		return "CronJob"
+	case "po", "pod", "pods":
+		return "Pod"
+	}
+	return ""
+}
+
+func (k *cmdKubectl) ListRevisions(r *Resource) ([]*Revision, error) {
+	var revisions []*Revision
+	var err error
+
+	kind := ResourceKind(r.Type)
+	switch kind {
+	case "Deployment":
+		revisions, err = k.listReplicaSetRevisions(r, kind)
+	case "StatefulSet", "DaemonSet":
+		revisions, err = k.listControllerRevisions(r, kind)
+	default:
+		return nil, fmt.Errorf("resource type %q does not support rollout", r.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions, nil
+}
+
+func (k *cmdKubectl) listReplicaSetRevisions(r *Resource, kind string) ([]*Revision, error) {
+	output, err := k.output(nil, "get", "-n", r.Namespace, "replicasets", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list replicaSetList
+	err = json.Unmarshal([]byte(output), &list)
+	if err != nil {
+		return nil, fmt.Errorf("decode replicasets: %w", err)
+	}
+
+	revisions := make([]*Revision, 0, len(list.Items))
+	for _, item := range list.Items {
+		if !item.Metadata.ownedBy(kind, r.Name) {
+			continue
+		}
+		number, err := strconv.ParseInt(item.Metadata.Annotations[annotationRevision], 10, 64)
+		if err != nil {
+			continue
+		}
+		revision, err := newRevision(&item.Metadata, number, item.Spec.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse replicaset %q: %w", item.Metadata.Name, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (k *cmdKubectl) listControllerRevisions(r *Resource, kind string) ([]*Revision, error) {
+	output, err := k.output(nil, "get", "-n", r.Namespace, "controllerrevisions", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list controllerRevisionList
+	err = json.Unmarshal([]byte(output), &list)
+	if err != nil {
+		return nil, fmt.Errorf("decode controllerrevisions: %w", err)
+	}
+
+	revisions := make([]*Revision, 0, len(list.Items))
+	for _, item := range list.Items {
+		if !item.Metadata.ownedBy(kind, r.Name) {
+			continue
+		}
+		revision, err := newRevision(&item.Metadata, item.Revision, item.Data.Spec.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse controllerrevision %q: %w", item.Metadata.Name, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func newRevision(meta *objectMeta, number int64, template json.RawMessage) (*Revision, error) {
+	template, err := cleanTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec struct {
+		Spec struct {
+			Containers []struct {
+				Image string `json:"image"`
+			} `json:"containers"`
+		} `json:"spec"`
+	}
+	err = json.Unmarshal(template, &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]string, 0, len(spec.Spec.Containers))
+	for _, c := range spec.Spec.Containers {
+		images = append(images, c.Image)
+	}
+
+	return &Revision{
+		Number:      number,
+		Name:        meta.Name,
+		Images:      images,
+		ChangeCause: meta.Annotations[annotationChangeCause],
+		CreateTime:  meta.CreationTimestamp,
+		Template:    template,
+	}, nil
+}
+
+// PodTemplate extracts the pod template from a workload in json format (the
+// output of `GetResource`).
+func PodTemplate(data []byte) ([]byte, error) {
+	var obj struct {
+		Spec struct {
+			Template json.RawMessage `json:"template"`
+		} `json:"spec"`
+	}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("decode resource: %w", err)
+	}
+	return cleanTemplate(obj.Spec.Template)
+}
+
+// cleanTemplate removes the fields that are added by controllers, so that the
+// template can be compared with the one in workload spec.
+func cleanTemplate(template json.RawMessage) ([]byte, error) {
+	if len(template) == 0 {
+		return nil, errors.New("pod template is empty")
+	}
+
+	var obj map[string]any
+	err := json.Unmarshal(template, &obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(obj, "$patch")
+
+	if meta, ok := obj["metadata"].(map[string]any); ok {
+		if labels, ok := meta["labels"].(map[string]any); ok {
+			delete(labels, labelPodTemplateHash)
+		}
+	}
+
+	return json.Marshal(obj)
+}
@@ -0,0 +1,88 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+const rulesReviewTemplate = `{
+  "apiVersion": "authorization.k8s.io/v1",
+  "kind": "SelfSubjectRulesReview",
+  "spec": {"namespace": %q}
+}`
+
+type resourceRule struct {
+	Verbs         []string `json:"verbs"`
+	APIGroups     []string `json:"apiGroups"`
+	Resources     []string `json:"resources"`
+	ResourceNames []string `json:"resourceNames"`
+}
+
+type rulesReview struct {
+	Status struct {
+		ResourceRules []*resourceRule `json:"resourceRules"`
+	} `json:"status"`
+}
+
+// selfReviewResources are granted to every user by the default cluster roles,
+// they say nothing about the namespaces.
+var selfReviewResources = []string{
+	"selfsubjectaccessreviews",
+	"selfsubjectrulesreviews",
+	"selfsubjectreviews",
+}
+
+// ListPermittedNamespaces uses SelfSubjectRulesReview (the same as
+// `kubectl auth can-i --list`) to find the namespaces allowed by name, such as
+// "get namespaces/foo".
+//
+// The queried namespace itself is not returned even if there are rules in it,
+// since the review returns the cluster-wide rules for any namespace, including
+// the ones that don't exist.
+func (k *cmdKubectl) ListPermittedNamespaces(namespace string) ([]string, error) {
+	body := fmt.Sprintf(rulesReviewTemplate, namespace)
+	output, err := k.query(bytes.NewReader([]byte(body)), "create", "-f", "-", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var review rulesReview
+	err = json.Unmarshal([]byte(output), &review)
+	if err != nil {
+		return nil, fmt.Errorf("decode rules review: %w", err)
+	}
+
+	var namespaces []string
+	for _, rule := range review.Status.ResourceRules {
+		if isSelfReviewRule(rule) {
+			continue
+		}
+		if !matchAny(rule.APIGroups, "") || !matchAny(rule.Resources, "namespaces") {
+			continue
+		}
+		if !matchAny(rule.Verbs, "get") && !matchAny(rule.Verbs, "list") {
+			continue
+		}
+		for _, name := range rule.ResourceNames {
+			if !slices.Contains(namespaces, name) {
+				namespaces = append(namespaces, name)
+			}
+		}
+	}
+	return namespaces, nil
+}
+
+func isSelfReviewRule(rule *resourceRule) bool {
+	for _, resource := range rule.Resources {
+		if !slices.Contains(selfReviewResources, resource) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(values []string, target string) bool {
+	return slices.Contains(values, target) || slices.Contains(values, "*")
+}
@@ -0,0 +1,112 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// The header of encrypted data, used to distinguish encrypted files from plain
+// ones.
+const magic = "KUBEWRAP-ENCRYPTED-V1\n"
+
+const (
+	KeySize  = 32
+	SaltSize = 16
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var ErrWrongKey = errors.New("decrypt failed, the key is wrong or the data is corrupted")
+
+// DeriveKey derives an AES-256 key from the passphrase with scrypt.
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	return key, nil
+}
+
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// Checksum returns a value that can verify the key without exposing it.
+func Checksum(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("kubewrap key checksum"))
+	return mac.Sum(nil)
+}
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Encrypt encrypts data with AES-GCM, the result format is:
+// magic + nonce + ciphertext.
+func Encrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	result := make([]byte, 0, len(magic)+len(nonce)+len(data)+gcm.Overhead())
+	result = append(result, magic...)
+	result = append(result, nonce...)
+	return gcm.Seal(result, nonce, data, []byte(magic)), nil
+}
+
+func Decrypt(key, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+	data = data[len(magic):]
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrWrongKey
+	}
+
+	nonce := data[:gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, data[gcm.NonceSize():], []byte(magic))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return gcm, nil
+}